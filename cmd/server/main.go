@@ -1,28 +1,172 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	"redditclone/internal/autocomplete"
+	"redditclone/internal/automod"
+	"redditclone/internal/ban"
+	"redditclone/internal/block"
 	"redditclone/internal/comment"
+	"redditclone/internal/events"
+	"redditclone/internal/filter"
+	"redditclone/internal/flair"
+	"redditclone/internal/karma"
+	"redditclone/internal/media"
+	"redditclone/internal/message"
 	"redditclone/internal/middleware"
+	"redditclone/internal/moderation"
+	"redditclone/internal/modlog"
+	"redditclone/internal/notification"
+	"redditclone/internal/nsfw"
 	"redditclone/internal/post"
+	"redditclone/internal/preferences"
+	"redditclone/internal/saved"
+	"redditclone/internal/search"
+	"redditclone/internal/stream"
 	"redditclone/internal/user"
+	"redditclone/internal/websocket"
 )
 
 func main() {
 	logger := log.New(os.Stdout, "redditclone: ", log.LstdFlags)
 
-	userService := user.NewUserService(logger)
-	postService := post.NewPostService(logger)
-	commentService := comment.NewCommentService()
+	if os.Getenv("JWT_SECRET_KEY") == "" {
+		logger.Fatal("JWT_SECRET_KEY не установлен")
+	}
 
-	authHandler := user.NewUserHandler(userService, logger)
-	postHandler := post.NewPostHandler(postService, userService, logger)
-	commentHandler := comment.NewCommentHandler(commentService, logger)
+	restoreWindow := durationFromEnv(logger, "RESTORE_WINDOW", 15*time.Minute)
+	retention := durationFromEnv(logger, "TOMBSTONE_RETENTION", 30*24*time.Hour)
+	if retention < restoreWindow {
+		retention = restoreWindow
+	}
+
+	archiveAfter := durationFromEnv(logger, "ARCHIVE_AFTER", 180*24*time.Hour)
+	pinLimit := intFromEnv(logger, "PIN_LIMIT", 2)
+
+	admins := listFromEnv("ADMIN_USERS")
+
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+
+	bus := events.NewBus()
+
+	userService := user.NewUserService(logger, bus)
+	postService := post.NewPostService(logger, bus, restoreWindow, retention)
+	commentService := comment.NewCommentService(postService, bus, restoreWindow, retention)
+	karmaService := karma.NewKarmaService(bus)
+	searchService := search.NewSearchService(postService, userService, bus, logger)
+	autocompleteService := autocomplete.NewAutocompleteService(postService, bus, logger)
+	savedService := saved.NewSavedService(postService, commentService, bus)
+	filterService := filter.NewFilterService(postService, userService)
+	blockService := block.NewBlockService(userService, postService, commentService)
+	moderationService := moderation.NewModerationService(admins, pinLimit, postService, commentService, userService, bus)
+	modlogService := modlog.NewModlogService(moderationService, userService, bus)
+	banService := ban.NewBanService(moderationService, userService, postService, bus)
+	flairService := flair.NewFlairService(postService, userService, moderationService, bus)
+	nsfwService := nsfw.NewNSFWService(postService, moderationService, bus)
+	preferencesService := preferences.NewPreferencesService(nsfwService)
+	mediaStorage, err := media.NewLocalStorage(mediaDir, "/media/")
+	if err != nil {
+		logger.Fatalf("Не удалось открыть каталог %s: %v\n", mediaDir, err)
+	}
+	mediaService := media.NewMediaService(mediaStorage, bus, logger)
+	messageService := message.NewMessageService(message.NewMemoryRepository(), userService, blockService, bus)
+
+	// AutoModerator действует только через сервисы, поэтому его пароль случайный и нигде не сохраняется.
+	autoModerator, err := userService.Register(automod.Username, randomPassword())
+	if err != nil {
+		logger.Fatalf("Не удалось создать пользователя %s: %v\n", automod.Username, err)
+	}
+	automodService := automod.NewAutomodService(autoModerator.ID, postService, commentService, userService, karmaService, moderationService, bus, logger)
+
+	// Уведомления создаются после AutoModerator, чтобы не сообщать о контенте, который он задержал.
+	notificationService := notification.NewNotificationService(postService, commentService, userService, blockService, preferencesService, bus)
+	notificationService.AddPostFilter(banService.HideShadowbannedPosts)
+	notificationService.AddPostFilter(moderationService.HideFilteredPosts)
+	notificationService.AddCommentFilter(banService.HideShadowbannedComments)
+	notificationService.AddCommentFilter(moderationService.HideFilteredComments)
+
+	postService.AddGuard(banService.PostGuard)
+	postService.AddGuard(blockService.PostGuard)
+	commentService.AddGuard(banService.CommentGuard)
+	commentService.AddGuard(blockService.CommentGuard)
+	commentService.AddGuard(moderationService.CommentGuard)
+
+	go purgeTombstones(logger, postService, commentService, time.Hour)
+	if archiveAfter > 0 {
+		go archivePosts(postService, archiveAfter, min(archiveAfter, time.Hour))
+	}
+
+	authHandler := user.NewUserHandler(userService, karmaService, logger)
+	karmaHandler := karma.NewKarmaHandler(karmaService, userService, logger)
+	postHandler := post.NewPostHandler(postService, userService, commentService, logger)
+	commentHandler := comment.NewCommentHandler(commentService, userService, logger)
+	searchHandler := search.NewSearchHandler(searchService, logger)
+	autocompleteHandler := autocomplete.NewAutocompleteHandler(autocompleteService, logger)
+	savedHandler := saved.NewSavedHandler(savedService, userService, logger)
+	filterHandler := filter.NewFilterHandler(filterService, logger)
+	blockHandler := block.NewBlockHandler(blockService, logger)
+	notificationHandler := notification.NewNotificationHandler(notificationService, logger)
+	messageHandler := message.NewMessageHandler(messageService, logger)
+	moderationHandler := moderation.NewModerationHandler(moderationService, logger)
+	modlogHandler := modlog.NewModlogHandler(modlogService, logger)
+	banHandler := ban.NewBanHandler(banService, logger)
+	automodHandler := automod.NewAutomodHandler(automodService, logger)
+	flairHandler := flair.NewFlairHandler(flairService, logger)
+	nsfwHandler := nsfw.NewNSFWHandler(nsfwService, logger)
+	preferencesHandler := preferences.NewPreferencesHandler(preferencesService, logger)
+	streamHandler := stream.NewStreamHandler(bus, postService, logger)
+	webSocketHandler := websocket.NewWebSocketHandler(bus, postService, userService, blockService, logger)
+
+	postHandler.AddListFilter(filterService.Filter)
+	postHandler.AddListFilter(nsfwService.Exclude)
+	postHandler.AddViewFilter(banService.HideShadowbannedPosts)
+	postHandler.AddViewFilter(moderationService.HideFilteredPosts)
+	postHandler.AddViewFilter(blockService.CollapsePosts)
+	postHandler.AddViewFilter(flairService.ShowUserFlair)
+	postHandler.AddViewFilter(nsfwService.Mark)
+	postHandler.SetFlairResolver(flairService.ResolvePostFlair)
+	postHandler.SetSortPreference(preferencesService.DefaultSort)
+	postHandler.SetImageStore(mediaService)
+	streamHandler.AddListFilter(nsfwService.Exclude)
+	streamHandler.AddViewFilter(banService.HideShadowbannedPosts)
+	streamHandler.AddViewFilter(moderationService.HideFilteredPosts)
+	streamHandler.AddViewFilter(nsfwService.Mark)
+	streamHandler.AddCommentFilter(banService.HideShadowbannedComments)
+	streamHandler.AddCommentFilter(moderationService.HideFilteredComments)
+	webSocketHandler.AddListFilter(nsfwService.Exclude)
+	webSocketHandler.AddViewFilter(banService.HideShadowbannedPosts)
+	webSocketHandler.AddViewFilter(moderationService.HideFilteredPosts)
+	webSocketHandler.AddViewFilter(nsfwService.Mark)
+	webSocketHandler.AddCommentFilter(banService.HideShadowbannedComments)
+	webSocketHandler.AddCommentFilter(moderationService.HideFilteredComments)
+	searchService.AddFilter(banService.HideShadowbannedPosts)
+	searchService.AddFilter(moderationService.HideFilteredPosts)
+	searchService.AddCommentFilter(banService.HideShadowbannedComments)
+	searchService.AddCommentFilter(moderationService.HideFilteredComments)
+	searchService.AddFilter(nsfwService.Exclude)
+	searchService.AddFilter(nsfwService.Mark)
+	autocompleteService.AddFilter(banService.HideShadowbannedPosts)
+	autocompleteService.AddFilter(moderationService.HideFilteredPosts)
+	autocompleteService.AddFilter(nsfwService.Exclude)
+	autocompleteService.AddCategoryFilter(nsfwService.ShowCategory)
+	postHandler.AddCommentFilter(banService.HideShadowbannedComments)
+	postHandler.AddCommentFilter(moderationService.HideFilteredComments)
+	postHandler.AddCommentFilter(blockService.CollapseComments)
+	commentHandler.AddListFilter(banService.HideShadowbannedUserComments)
+	commentHandler.AddListFilter(moderationService.HideFilteredUserComments)
 
 	router := mux.NewRouter()
 
@@ -31,23 +175,180 @@ func main() {
 	api.HandleFunc("/register", authHandler.Register).Methods("POST")
 	api.HandleFunc("/login", authHandler.Login).Methods("POST")
 
-	api.HandleFunc("/posts", postHandler.GetAllPosts).Methods("GET")
+	api.HandleFunc("/posts", middleware.OptionalJWTMiddleware(postHandler.GetAllPosts)).Methods("GET")
 	api.HandleFunc("/posts", middleware.JWTMiddleware(postHandler.CreatePost)).Methods("POST")
-	api.HandleFunc("/posts/{category}", postHandler.GetPostsByCategory).Methods("GET")
-	api.HandleFunc("/post/{postID}", postHandler.GetPostDetails).Methods("GET")
+	api.HandleFunc("/posts/image", middleware.JWTMiddleware(postHandler.CreateImagePost)).Methods("POST")
+	api.HandleFunc("/posts/{category}", middleware.OptionalJWTMiddleware(postHandler.GetPostsByCategory)).Methods("GET")
+	api.HandleFunc("/post/{postID}", middleware.OptionalJWTMiddleware(postHandler.GetPostDetails)).Methods("GET")
 	api.HandleFunc("/post/{postID}", middleware.JWTMiddleware(postHandler.DeletePost)).Methods("DELETE")
+	api.HandleFunc("/post/{postID}/restore", middleware.JWTMiddleware(postHandler.RestorePost)).Methods("POST")
 	api.HandleFunc("/post/{postID}/upvote", middleware.JWTMiddleware(postHandler.UpvotePost)).Methods("GET")
 	api.HandleFunc("/post/{postID}/downvote", middleware.JWTMiddleware(postHandler.DownvotePost)).Methods("GET")
 	api.HandleFunc("/post/{postID}/unvote", middleware.JWTMiddleware(postHandler.UnvotePost)).Methods("GET")
-	api.HandleFunc("/user/{userLogin}", postHandler.GetPostsByUser).Methods("GET")
+	api.HandleFunc("/user/{userLogin}", middleware.OptionalJWTMiddleware(postHandler.GetPostsByUser)).Methods("GET")
 
 	api.HandleFunc("/post/{postID}/comment", middleware.JWTMiddleware(commentHandler.AddComment)).Methods("POST")
 	api.HandleFunc("/post/{postID}/comment/{commentID}", middleware.JWTMiddleware(commentHandler.DeleteComment)).Methods("DELETE")
+	api.HandleFunc("/post/{postID}/comment/{commentID}/restore", middleware.JWTMiddleware(commentHandler.RestoreComment)).Methods("POST")
+	api.HandleFunc("/post/{postID}/comment/{commentID}/upvote", middleware.JWTMiddleware(commentHandler.UpvoteComment)).Methods("GET")
+	api.HandleFunc("/post/{postID}/comment/{commentID}/downvote", middleware.JWTMiddleware(commentHandler.DownvoteComment)).Methods("GET")
+	api.HandleFunc("/post/{postID}/comment/{commentID}/unvote", middleware.JWTMiddleware(commentHandler.UnvoteComment)).Methods("GET")
+	api.HandleFunc("/user/{userLogin}/comments", middleware.OptionalJWTMiddleware(commentHandler.GetCommentsByUser)).Methods("GET")
+	api.HandleFunc("/user/{userLogin}/profile", authHandler.GetProfile).Methods("GET")
+	api.HandleFunc("/me/profile", middleware.JWTMiddleware(authHandler.UpdateProfile)).Methods("PUT")
+
+	api.HandleFunc("/post/{postID}/save", middleware.JWTMiddleware(savedHandler.SavePost)).Methods("POST")
+	api.HandleFunc("/post/{postID}/unsave", middleware.JWTMiddleware(savedHandler.UnsavePost)).Methods("POST")
+	api.HandleFunc("/post/{postID}/comment/{commentID}/save", middleware.JWTMiddleware(savedHandler.SaveComment)).Methods("POST")
+	api.HandleFunc("/post/{postID}/comment/{commentID}/unsave", middleware.JWTMiddleware(savedHandler.UnsaveComment)).Methods("POST")
+	api.HandleFunc("/user/{userLogin}/saved", middleware.JWTMiddleware(savedHandler.GetSaved)).Methods("GET")
+
+	api.HandleFunc("/post/{postID}/hide", middleware.JWTMiddleware(filterHandler.HidePost)).Methods("POST")
+	api.HandleFunc("/post/{postID}/unhide", middleware.JWTMiddleware(filterHandler.UnhidePost)).Methods("POST")
+	api.HandleFunc("/me/filters", middleware.JWTMiddleware(filterHandler.GetSettings)).Methods("GET")
+	api.HandleFunc("/me/filters/{kind}/{value}", middleware.JWTMiddleware(filterHandler.Mute)).Methods("PUT")
+	api.HandleFunc("/me/filters/{kind}/{value}", middleware.JWTMiddleware(filterHandler.Unmute)).Methods("DELETE")
+
+	api.HandleFunc("/me/blocks", middleware.JWTMiddleware(blockHandler.GetBlocked)).Methods("GET")
+	api.HandleFunc("/me/blocks/{userLogin}", middleware.JWTMiddleware(blockHandler.Block)).Methods("PUT")
+	api.HandleFunc("/me/blocks/{userLogin}", middleware.JWTMiddleware(blockHandler.Unblock)).Methods("DELETE")
+	api.HandleFunc("/notifications", middleware.JWTMiddleware(notificationHandler.GetNotifications)).Methods("GET")
+	api.HandleFunc("/notifications/unread", middleware.JWTMiddleware(notificationHandler.GetUnreadCount)).Methods("GET")
+	api.HandleFunc("/notifications/read", middleware.JWTMiddleware(notificationHandler.MarkAllRead)).Methods("POST")
+	api.HandleFunc("/notifications/{notificationID}/read", middleware.JWTMiddleware(notificationHandler.MarkRead)).Methods("POST")
+
+	api.HandleFunc("/post/{postID}/lock", middleware.JWTMiddleware(moderationHandler.LockPost)).Methods("POST")
+	api.HandleFunc("/post/{postID}/unlock", middleware.JWTMiddleware(moderationHandler.UnlockPost)).Methods("POST")
+	api.HandleFunc("/post/{postID}/pin", middleware.JWTMiddleware(moderationHandler.PinPost)).Methods("POST")
+	api.HandleFunc("/post/{postID}/unpin", middleware.JWTMiddleware(moderationHandler.UnpinPost)).Methods("POST")
+	api.HandleFunc("/post/{postID}/flair", middleware.JWTMiddleware(flairHandler.SetPostFlair)).Methods("PUT")
+	api.HandleFunc("/post/{postID}/marks", middleware.JWTMiddleware(nsfwHandler.MarkPost)).Methods("PUT")
+	api.HandleFunc("/me/preferences", middleware.JWTMiddleware(preferencesHandler.GetPreferences)).Methods("GET")
+	api.HandleFunc("/me/preferences", middleware.JWTMiddleware(preferencesHandler.UpdatePreferences)).Methods("PUT")
+	api.HandleFunc("/me/content", middleware.JWTMiddleware(nsfwHandler.GetSettings)).Methods("GET")
+	api.HandleFunc("/me/content", middleware.JWTMiddleware(nsfwHandler.UpdateSettings)).Methods("PUT")
+	api.HandleFunc("/mod/{category}/nsfw", middleware.JWTMiddleware(nsfwHandler.MarkCategory)).Methods("PUT")
+	api.HandleFunc("/mod/{category}/nsfw", middleware.JWTMiddleware(nsfwHandler.UnmarkCategory)).Methods("DELETE")
+	api.HandleFunc("/flair/{category}", flairHandler.GetTemplates).Methods("GET")
+	api.HandleFunc("/flair/{category}/me", middleware.JWTMiddleware(flairHandler.SetUserFlair)).Methods("PUT")
+	api.HandleFunc("/mod/{category}/flair", middleware.JWTMiddleware(flairHandler.AddTemplate)).Methods("POST")
+	api.HandleFunc("/mod/{category}/flair/{templateID}", middleware.JWTMiddleware(flairHandler.DeleteTemplate)).Methods("DELETE")
+	api.HandleFunc("/mod/{category}/flair/users/{userLogin}", middleware.JWTMiddleware(flairHandler.SetUserFlair)).Methods("PUT")
+	api.HandleFunc("/post/{postID}/report", middleware.JWTMiddleware(moderationHandler.ReportPost)).Methods("POST")
+	api.HandleFunc("/post/{postID}/comment/{commentID}/report", middleware.JWTMiddleware(moderationHandler.ReportComment)).Methods("POST")
+	api.HandleFunc("/mod/{category}/queue", middleware.JWTMiddleware(moderationHandler.GetQueue)).Methods("GET")
+	api.HandleFunc("/mod/{category}/queue", middleware.JWTMiddleware(moderationHandler.Act)).Methods("POST")
+	api.HandleFunc("/mod/{category}/log", middleware.OptionalJWTMiddleware(modlogHandler.GetLog)).Methods("GET")
+	api.HandleFunc("/mod/{category}/log/settings", modlogHandler.GetSettings).Methods("GET")
+	api.HandleFunc("/mod/{category}/log/settings", middleware.JWTMiddleware(modlogHandler.UpdateSettings)).Methods("PUT")
+	api.HandleFunc("/mod/{category}/bans", middleware.JWTMiddleware(banHandler.GetBans)).Methods("GET")
+	api.HandleFunc("/mod/{category}/bans/{userLogin}", middleware.JWTMiddleware(banHandler.Ban)).Methods("PUT")
+	api.HandleFunc("/mod/{category}/bans/{userLogin}", middleware.JWTMiddleware(banHandler.Unban)).Methods("DELETE")
+	api.HandleFunc("/bans", middleware.JWTMiddleware(banHandler.GetBans)).Methods("GET")
+	api.HandleFunc("/bans/{userLogin}", middleware.JWTMiddleware(banHandler.Ban)).Methods("PUT")
+	api.HandleFunc("/bans/{userLogin}", middleware.JWTMiddleware(banHandler.Unban)).Methods("DELETE")
+	api.HandleFunc("/modlog", middleware.OptionalJWTMiddleware(modlogHandler.GetLog)).Methods("GET")
+	api.HandleFunc("/mod/{category}/automod", middleware.JWTMiddleware(automodHandler.GetConfig)).Methods("GET")
+	api.HandleFunc("/mod/{category}/automod", middleware.JWTMiddleware(automodHandler.UpdateConfig)).Methods("PUT")
+	api.HandleFunc("/mod/{category}/moderators", moderationHandler.GetModerators).Methods("GET")
+	api.HandleFunc("/mod/{category}/moderators/{userLogin}", middleware.JWTMiddleware(moderationHandler.AddModerator)).Methods("PUT")
+	api.HandleFunc("/mod/{category}/moderators/{userLogin}", middleware.JWTMiddleware(moderationHandler.RemoveModerator)).Methods("DELETE")
+
+	api.HandleFunc("/messages", middleware.JWTMiddleware(messageHandler.GetConversations)).Methods("GET")
+	api.HandleFunc("/messages/unread", middleware.JWTMiddleware(messageHandler.GetUnreadCount)).Methods("GET")
+	api.HandleFunc("/messages/conversation/{conversationID}", middleware.JWTMiddleware(messageHandler.GetMessages)).Methods("GET")
+	api.HandleFunc("/messages/conversation/{conversationID}/read", middleware.JWTMiddleware(messageHandler.MarkRead)).Methods("POST")
+	api.HandleFunc("/messages/{userLogin}", middleware.JWTMiddleware(messageHandler.Send)).Methods("POST")
+
+	api.HandleFunc("/stream", streamHandler.Stream).Methods("GET")
+	api.HandleFunc("/ws", webSocketHandler.Connect).Methods("GET")
+
+	api.HandleFunc("/leaderboard", karmaHandler.GetLeaderboard).Methods("GET")
+	api.HandleFunc("/leaderboard/{category}", karmaHandler.GetLeaderboard).Methods("GET")
+	api.HandleFunc("/search", middleware.OptionalJWTMiddleware(searchHandler.Search)).Methods("GET")
+	api.HandleFunc("/autocomplete", middleware.OptionalJWTMiddleware(autocompleteHandler.Suggest)).Methods("GET")
 
 	staticFileDirectory := http.Dir("redditclone/static/")
 	staticFileHandler := http.StripPrefix("/static/", http.FileServer(staticFileDirectory))
 	router.PathPrefix("/static/").Handler(staticFileHandler)
+	router.PathPrefix("/media/").Handler(http.StripPrefix("/media/", http.FileServer(media.NewFileSystem(mediaDir))))
 
 	logger.Printf("Сервер запущен на %s\n", "http://localhost:8080")
 	log.Fatal(http.ListenAndServe(":8080", router))
 }
+
+// durationFromEnv читает длительность вида "15m" или "720h" из переменной окружения.
+func durationFromEnv(logger *log.Logger, name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		logger.Printf("Некорректное значение %s=%q, используется %s\n", name, value, def)
+		return def
+	}
+	return d
+}
+
+// intFromEnv читает неотрицательное целое число из переменной окружения.
+func intFromEnv(logger *log.Logger, name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		logger.Printf("Некорректное значение %s=%q, используется %d\n", name, value, def)
+		return def
+	}
+	return n
+}
+
+// listFromEnv читает список через запятую из переменной окружения.
+func listFromEnv(name string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// randomPassword возвращает пароль, который невозможно угадать.
+func randomPassword() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// purgeTombstones периодически окончательно удаляет посты и комментарии,
+// срок хранения которых после мягкого удаления истёк.
+func purgeTombstones(logger *log.Logger, posts post.Service, comments comment.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purgedPosts := posts.PurgeDeleted()
+		purgedComments := comments.PurgeDeleted(purgedPosts...)
+		if len(purgedPosts) > 0 || purgedComments > 0 {
+			logger.Printf("Очистка: удалено постов %d, комментариев %d\n", len(purgedPosts), purgedComments)
+		}
+	}
+}
+
+// archivePosts периодически архивирует посты старше age: за них больше нельзя
+// голосовать и их нельзя комментировать.
+func archivePosts(posts post.Service, age, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		posts.ArchiveOlderThan(age)
+	}
+}
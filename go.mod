@@ -3,7 +3,7 @@ module redditclone
 go 1.23
 
 require (
-	github.com/dgrijalva/jwt-go v3.2.0+incompatible // indirect
-	github.com/gorilla/mux v1.8.1 // indirect
-	golang.org/x/crypto v0.28.0 // indirect
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/gorilla/mux v1.8.1
+	golang.org/x/crypto v0.28.0
 )
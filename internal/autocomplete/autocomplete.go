@@ -0,0 +1,20 @@
+package autocomplete
+
+const (
+	KindCategory = "category"
+	KindUser     = "user"
+	KindTitle    = "title"
+)
+
+// Suggestion is a single completion. Score is the popularity it is ranked
+// by: the number of live posts for a category, post karma for a user and the
+// post's rating for a title.
+type Suggestion struct {
+	Kind   string `json:"kind"`
+	Text   string `json:"text"`
+	PostID int    `json:"post_id,omitempty"`
+	Score  int    `json:"score"`
+}
+
+// CategoryFilter reports whether the viewer may be offered the category.
+type CategoryFilter func(viewerID int, category string) bool
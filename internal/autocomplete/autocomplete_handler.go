@@ -0,0 +1,58 @@
+package autocomplete
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 50
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewAutocompleteHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) Suggest(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting autocomplete suggestions")
+
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		http.Error(w, "Missing prefix", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxLimit)
+	}
+
+	viewerID, _ := r.Context().Value("userID").(int)
+	suggestions, err := h.service.Suggest(viewerID, prefix, r.URL.Query().Get("kind"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(suggestions); err != nil {
+		http.Error(w, "Failed to encode suggestions", http.StatusInternalServerError)
+		return
+	}
+}
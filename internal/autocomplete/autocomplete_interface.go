@@ -0,0 +1,9 @@
+package autocomplete
+
+import "redditclone/internal/post"
+
+type Service interface {
+	Suggest(viewerID int, prefix, kind string, limit int) ([]Suggestion, error)
+	AddFilter(filter post.ListFilter)
+	AddCategoryFilter(filter CategoryFilter)
+}
@@ -0,0 +1,276 @@
+package autocomplete
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"redditclone/internal/events"
+	"redditclone/internal/post"
+	"redditclone/internal/user"
+)
+
+var ErrUnknownKind = errors.New("kind must be category, user or title")
+
+type entry struct {
+	suggestion Suggestion
+	texts      []string
+}
+
+type autocompleteService struct {
+	mu      sync.RWMutex
+	tries   map[string]*trie
+	entries map[string]*entry
+
+	filters         []post.ListFilter
+	categoryFilters []CategoryFilter
+
+	postService post.Service
+	logger      *log.Logger
+}
+
+// NewAutocompleteService keeps one trie per suggestion kind and updates them
+// from bus events as users register and posts are created, voted on and
+// deleted.
+func NewAutocompleteService(postService post.Service, bus *events.Bus, logger *log.Logger) Service {
+	s := &autocompleteService{
+		entries:     make(map[string]*entry),
+		postService: postService,
+		logger:      logger,
+	}
+	s.tries = map[string]*trie{
+		KindCategory: newTrie(s.suggestion),
+		KindUser:     newTrie(s.suggestion),
+		KindTitle:    newTrie(s.suggestion),
+	}
+
+	bus.Subscribe(events.UserRegistered, s.userRegistered)
+	bus.Subscribe(events.PostCreated, s.postAdded)
+	bus.Subscribe(events.PostRestored, s.postAdded)
+	bus.Subscribe(events.PostDeleted, s.postRemoved)
+	bus.Subscribe(events.PostVoted, s.postVoted)
+
+	return s
+}
+
+// AddFilter registers a step that the post behind every title suggestion
+// goes through; titles whose post it drops are not suggested. Filters must be
+// added before the service starts handling requests.
+func (s *autocompleteService) AddFilter(filter post.ListFilter) {
+	s.filters = append(s.filters, filter)
+}
+
+// AddCategoryFilter registers a check that every category suggestion must
+// pass.
+func (s *autocompleteService) AddCategoryFilter(filter CategoryFilter) {
+	s.categoryFilters = append(s.categoryFilters, filter)
+}
+
+// Suggest takes the best limit matches and filters them for the viewer in
+// order. When the filters drop some, it tries again with twice as many
+// matches, so that up to limit suggestions are returned however many are
+// dropped.
+func (s *autocompleteService) Suggest(viewerID int, prefix, kind string, limit int) ([]Suggestion, error) {
+	kinds := []string{KindCategory, KindUser, KindTitle}
+	if kind != "" {
+		if _, ok := s.tries[kind]; !ok {
+			return nil, ErrUnknownKind
+		}
+		kinds = []string{kind}
+	}
+
+	for want := limit; ; want *= 2 {
+		matches := s.matches(kinds, prefix, want)
+		suggestions := []Suggestion{}
+		for _, suggestion := range matches {
+			if limit > 0 && len(suggestions) == limit {
+				break
+			}
+			if s.visible(viewerID, suggestion) {
+				suggestions = append(suggestions, suggestion)
+			}
+		}
+		if limit <= 0 || len(suggestions) == limit || len(matches) < want {
+			return suggestions, nil
+		}
+	}
+}
+
+// matches returns the best n suggestions of kinds under prefix, or all of
+// them when n is not positive, highest score first.
+func (s *autocompleteService) matches(kinds []string, prefix string, n int) []Suggestion {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := []Suggestion{}
+	for _, k := range kinds {
+		for _, key := range s.tries[k].top(prefix, n) {
+			matches = append(matches, s.entries[key].suggestion)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Text < matches[j].Text
+	})
+	if n > 0 && len(matches) > n {
+		matches = matches[:n]
+	}
+	return matches
+}
+
+func (s *autocompleteService) visible(viewerID int, suggestion Suggestion) bool {
+	switch suggestion.Kind {
+	case KindCategory:
+		for _, filter := range s.categoryFilters {
+			if !filter(viewerID, suggestion.Text) {
+				return false
+			}
+		}
+	case KindTitle:
+		p, err := s.postService.GetPostByID(suggestion.PostID)
+		if err != nil {
+			return false
+		}
+		posts := []post.Post{p}
+		for _, filter := range s.filters {
+			posts = filter(viewerID, posts)
+		}
+		return len(posts) > 0
+	}
+	return true
+}
+
+func (s *autocompleteService) userRegistered(e events.Event) {
+	u, ok := e.Payload.(user.User)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.add(userKey(u.ID), Suggestion{Kind: KindUser, Text: u.Username}, u.Username)
+}
+
+func (s *autocompleteService) postAdded(e events.Event) {
+	p, ok := e.Payload.(post.Post)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.Title != "" {
+		s.add(titleKey(p.ID), Suggestion{Kind: KindTitle, Text: p.Title, PostID: p.ID, Score: p.Score()}, wordSuffixes(p.Title)...)
+	}
+
+	key := categoryKey(p.Category)
+	if _, exists := s.entries[key]; !exists {
+		s.add(key, Suggestion{Kind: KindCategory, Text: p.Category}, p.Category)
+	}
+	s.setScore(key, s.entries[key].suggestion.Score+1)
+}
+
+func (s *autocompleteService) postRemoved(e events.Event) {
+	p, ok := e.Payload.(post.Post)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(titleKey(p.ID))
+
+	key := categoryKey(p.Category)
+	if entry, exists := s.entries[key]; exists {
+		if entry.suggestion.Score <= 1 {
+			s.remove(key)
+		} else {
+			s.setScore(key, entry.suggestion.Score-1)
+		}
+	}
+}
+
+func (s *autocompleteService) postVoted(e events.Event) {
+	vote, ok := e.Payload.(post.VoteEvent)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[titleKey(vote.Post.ID)]; exists {
+		s.setScore(titleKey(vote.Post.ID), vote.Post.Score())
+	}
+	if entry, exists := s.entries[userKey(vote.Post.AuthorID)]; exists {
+		s.setScore(userKey(vote.Post.AuthorID), entry.suggestion.Score+vote.Vote-vote.Previous)
+	}
+}
+
+// add stores a suggestion reachable through each of texts. The caller holds
+// s.mu.
+func (s *autocompleteService) add(key string, suggestion Suggestion, texts ...string) {
+	s.remove(key)
+	s.entries[key] = &entry{suggestion: suggestion, texts: texts}
+	for _, text := range texts {
+		s.tries[suggestion.Kind].insert(text, key)
+	}
+}
+
+func (s *autocompleteService) remove(key string) {
+	entry, exists := s.entries[key]
+	if !exists {
+		return
+	}
+
+	for _, text := range entry.texts {
+		s.tries[entry.suggestion.Kind].remove(text, key)
+	}
+	delete(s.entries, key)
+}
+
+// setScore changes the score of key's suggestion and reranks it in its trie.
+// The caller holds s.mu.
+func (s *autocompleteService) setScore(key string, score int) {
+	entry := s.entries[key]
+	entry.suggestion.Score = score
+	for _, text := range entry.texts {
+		s.tries[entry.suggestion.Kind].rerank(text, key)
+	}
+}
+
+// suggestion is the rank lookup of the tries. The caller holds s.mu.
+func (s *autocompleteService) suggestion(key string) Suggestion {
+	return s.entries[key].suggestion
+}
+
+// wordSuffixes lets a title be completed from the start of any of its words.
+func wordSuffixes(title string) []string {
+	words := strings.Fields(title)
+	suffixes := make([]string, 0, len(words))
+	for i := range words {
+		suffixes = append(suffixes, strings.Join(words[i:], " "))
+	}
+	return suffixes
+}
+
+func categoryKey(category string) string {
+	return KindCategory + ":" + category
+}
+
+func userKey(id int) string {
+	return KindUser + ":" + strconv.Itoa(id)
+}
+
+func titleKey(postID int) string {
+	return KindTitle + ":" + strconv.Itoa(postID)
+}
@@ -0,0 +1,191 @@
+package autocomplete
+
+import (
+	"container/heap"
+	"strings"
+)
+
+// ranked is a key with the score and text it is ordered by: higher scores
+// first, then texts and keys alphabetically.
+type ranked struct {
+	key   string
+	score int
+	text  string
+}
+
+func (a ranked) before(b ranked) bool {
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	if a.text != b.text {
+		return a.text < b.text
+	}
+	return a.key < b.key
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	keys     map[string]struct{}
+	// best is the highest ranked key in the node's subtree, with an empty key
+	// when the subtree holds none.
+	best ranked
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{
+		children: make(map[rune]*trieNode),
+		keys:     make(map[string]struct{}),
+	}
+}
+
+// trie maps lowercase strings to the keys of the suggestions they complete.
+// A suggestion may be reachable through several strings, e.g. a title through
+// each of its words. rank looks up the suggestion a key stands for; whenever
+// its score changes, rerank must be called for each of its strings.
+type trie struct {
+	root *trieNode
+	rank func(key string) Suggestion
+}
+
+func newTrie(rank func(key string) Suggestion) *trie {
+	return &trie{root: newTrieNode(), rank: rank}
+}
+
+func (t *trie) ranked(key string) ranked {
+	s := t.rank(key)
+	return ranked{key: key, score: s.Score, text: s.Text}
+}
+
+// path returns the nodes from the root to text's node, or nil when text is
+// not in the trie and create is false.
+func (t *trie) path(text string, create bool) []*trieNode {
+	path := []*trieNode{t.root}
+	node := t.root
+	for _, r := range strings.ToLower(text) {
+		child, ok := node.children[r]
+		if !ok {
+			if !create {
+				return nil
+			}
+			child = newTrieNode()
+			node.children[r] = child
+		}
+		node = child
+		path = append(path, node)
+	}
+	return path
+}
+
+func (t *trie) insert(text, key string) {
+	path := t.path(text, true)
+	path[len(path)-1].keys[key] = struct{}{}
+	t.update(path)
+}
+
+// remove drops key from text's node and prunes branches left empty.
+func (t *trie) remove(text, key string) {
+	path := t.path(text, false)
+	if path == nil {
+		return
+	}
+	delete(path[len(path)-1].keys, key)
+
+	runes := []rune(strings.ToLower(text))
+	for i := len(runes); i > 0; i-- {
+		n := path[i]
+		if len(n.keys) > 0 || len(n.children) > 0 {
+			break
+		}
+		delete(path[i-1].children, runes[i-1])
+	}
+	t.update(path)
+}
+
+// rerank updates the trie after the score of key, stored under text, changed.
+func (t *trie) rerank(text, key string) {
+	if path := t.path(text, false); path != nil {
+		t.update(path)
+	}
+}
+
+// update recomputes best from the end of path upwards, stopping at the first
+// node whose best is unchanged, as its ancestors then are too.
+func (t *trie) update(path []*trieNode) {
+	for i := len(path) - 1; i >= 0; i-- {
+		n := path[i]
+		var best ranked
+		for key := range n.keys {
+			if r := t.ranked(key); best.key == "" || r.before(best) {
+				best = r
+			}
+		}
+		for _, child := range n.children {
+			if child.best.key != "" && (best.key == "" || child.best.before(best)) {
+				best = child.best
+			}
+		}
+		if best == n.best {
+			return
+		}
+		n.best = best
+	}
+}
+
+// top returns up to n keys stored under prefix, all of them when n is not
+// positive, in ranked order. It walks the subtree best first and stops as
+// soon as it has n keys.
+func (t *trie) top(prefix string, n int) []string {
+	path := t.path(prefix, false)
+	if path == nil {
+		return nil
+	}
+	node := path[len(path)-1]
+	if node.best.key == "" {
+		return nil
+	}
+
+	keys := []string{}
+	seen := make(map[string]struct{})
+	queue := &rankQueue{{ranked: node.best, node: node}}
+	for queue.Len() > 0 && (n <= 0 || len(keys) < n) {
+		item := heap.Pop(queue).(rankItem)
+		if item.node == nil {
+			if _, ok := seen[item.key]; !ok {
+				seen[item.key] = struct{}{}
+				keys = append(keys, item.key)
+			}
+			continue
+		}
+		for key := range item.node.keys {
+			heap.Push(queue, rankItem{ranked: t.ranked(key)})
+		}
+		for _, child := range item.node.children {
+			if child.best.key != "" {
+				heap.Push(queue, rankItem{ranked: child.best, node: child})
+			}
+		}
+	}
+	return keys
+}
+
+// rankItem is a key, or a node ranked by the best key below it, waiting to
+// be visited by top.
+type rankItem struct {
+	ranked
+	node *trieNode
+}
+
+type rankQueue []rankItem
+
+func (q rankQueue) Len() int           { return len(q) }
+func (q rankQueue) Less(i, j int) bool { return q[i].before(q[j].ranked) }
+func (q rankQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *rankQueue) Push(x any) { *q = append(*q, x.(rankItem)) }
+
+func (q *rankQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
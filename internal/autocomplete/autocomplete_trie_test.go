@@ -0,0 +1,151 @@
+package autocomplete
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testTrie is a trie over suggestions kept in a map, with the texts each key
+// is stored under.
+type testTrie struct {
+	*trie
+	suggestions map[string]Suggestion
+	texts       map[string][]string
+}
+
+func newTestTrie() *testTrie {
+	tt := &testTrie{suggestions: make(map[string]Suggestion), texts: make(map[string][]string)}
+	tt.trie = newTrie(func(key string) Suggestion { return tt.suggestions[key] })
+	return tt
+}
+
+func (tt *testTrie) add(key string, score int, texts ...string) {
+	tt.suggestions[key] = Suggestion{Text: texts[0], Score: score}
+	tt.texts[key] = texts
+	for _, text := range texts {
+		tt.insert(text, key)
+	}
+}
+
+func (tt *testTrie) setScore(key string, score int) {
+	s := tt.suggestions[key]
+	s.Score = score
+	tt.suggestions[key] = s
+	for _, text := range tt.texts[key] {
+		tt.rerank(text, key)
+	}
+}
+
+func (tt *testTrie) drop(key string) {
+	for _, text := range tt.texts[key] {
+		tt.remove(text, key)
+	}
+	delete(tt.suggestions, key)
+	delete(tt.texts, key)
+}
+
+// want ranks every key with a text under prefix the slow way.
+func (tt *testTrie) want(prefix string, n int) []string {
+	matches := []ranked{}
+	for key, texts := range tt.texts {
+		for _, text := range texts {
+			if strings.HasPrefix(strings.ToLower(text), strings.ToLower(prefix)) {
+				matches = append(matches, tt.ranked(key))
+				break
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].before(matches[j]) })
+
+	keys := []string{}
+	for _, m := range matches {
+		if n > 0 && len(keys) == n {
+			break
+		}
+		keys = append(keys, m.key)
+	}
+	return keys
+}
+
+func TestTrieTop(t *testing.T) {
+	tt := newTestTrie()
+	tt.add("a", 5, "golang", "lang")
+	tt.add("b", 9, "go")
+	tt.add("c", 5, "gopher")
+	tt.add("d", 1, "Google")
+
+	tests := []struct {
+		prefix string
+		n      int
+		want   []string
+	}{
+		{"go", 0, []string{"b", "a", "c", "d"}},
+		{"go", 2, []string{"b", "a"}},
+		{"GOO", 0, []string{"d"}},
+		{"la", 0, []string{"a"}},
+		{"", 1, []string{"b"}},
+		{"gox", 0, nil},
+	}
+	for _, test := range tests {
+		if got := tt.top(test.prefix, test.n); fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("top(%q, %d) = %v, want %v", test.prefix, test.n, got, test.want)
+		}
+	}
+
+	tt.setScore("d", 10)
+	tt.setScore("b", 0)
+	if got, want := tt.top("go", 3), []string{"d", "a", "c"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("after rerank: top = %v, want %v", got, want)
+	}
+
+	tt.drop("a")
+	if got := tt.top("la", 0); len(got) != 0 {
+		t.Errorf("after remove: top = %v", got)
+	}
+	if _, ok := tt.root.children['l']; ok {
+		t.Error("removed branch is not pruned")
+	}
+}
+
+func TestTrieTopRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	word := func(length int) string {
+		b := make([]byte, length)
+		for i := range b {
+			b[i] = "abc"[rng.Intn(3)]
+		}
+		return string(b)
+	}
+
+	tt := newTestTrie()
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprint(rng.Intn(60))
+		switch _, exists := tt.texts[key]; {
+		case !exists:
+			tt.add(key, rng.Intn(10), word(1+rng.Intn(4)), word(1+rng.Intn(4)))
+		case rng.Intn(3) == 0:
+			tt.drop(key)
+		default:
+			tt.setScore(key, rng.Intn(10))
+		}
+
+		prefix, n := word(rng.Intn(3)), rng.Intn(6)
+		if got, want := tt.top(prefix, n), tt.want(prefix, n); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("step %d: top(%q, %d) = %v, want %v", i, prefix, n, got, want)
+		}
+	}
+}
+
+func BenchmarkTrieTop(b *testing.B) {
+	tt := newTestTrie()
+	for i := 0; i < 100000; i++ {
+		tt.add(fmt.Sprint(i), i%1000, fmt.Sprintf("user%d", i))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tt.top("u", 10)
+	}
+}
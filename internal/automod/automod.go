@@ -0,0 +1,46 @@
+package automod
+
+import "redditclone/internal/post"
+
+// Username is the account AutoModerator acts as.
+const Username = "AutoModerator"
+
+const (
+	TypePost    = "post"
+	TypeComment = "comment"
+	TypeAny     = "any"
+)
+
+const (
+	ActionRemove = "remove"
+	ActionFilter = "filter"
+	ActionFlair  = "flair"
+	ActionLock   = "lock"
+	ActionReply  = "reply"
+)
+
+// Rule matches new posts and comments of Type (any by default) when all of
+// its conditions hold. Title, Body and Domains match if any entry does;
+// title and body entries are regular expressions. AccountAgeBelow is in
+// days, KarmaBelow is the author's combined karma. A rule with ReportsAtLeast
+// is checked when content is reported rather than when it is created, and
+// fires once, when the number of reports reaches it.
+type Rule struct {
+	Name            string      `json:"name"`
+	Type            string      `json:"type,omitempty"`
+	Title           []string    `json:"title,omitempty"`
+	Body            []string    `json:"body,omitempty"`
+	Domains         []string    `json:"domains,omitempty"`
+	AccountAgeBelow *int        `json:"account_age_below,omitempty"`
+	KarmaBelow      *int        `json:"karma_below,omitempty"`
+	ReportsAtLeast  int         `json:"reports_at_least,omitempty"`
+	Actions         []string    `json:"actions"`
+	Flair           *post.Flair `json:"flair,omitempty"`
+	Reply           string      `json:"reply,omitempty"`
+	Reason          string      `json:"reason,omitempty"`
+}
+
+// Config is a category's rule set.
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
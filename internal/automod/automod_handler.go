@@ -0,0 +1,88 @@
+package automod
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"redditclone/internal/moderation"
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewAutomodHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting AutoModerator rules")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	config, err := h.service.GetConfig(userID, mux.Vars(r)["category"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.writeConfig(w, config)
+}
+
+func (h *Handler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Updating AutoModerator rules")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var config Config
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		http.Error(w, "Invalid rules: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	config, err := h.service.UpdateConfig(userID, mux.Vars(r)["category"], config)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.writeConfig(w, config)
+}
+
+func (h *Handler) writeConfig(w http.ResponseWriter, config Config) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(config); err != nil {
+		http.Error(w, "Failed to encode rules", http.StatusInternalServerError)
+		return
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var configErr *ConfigError
+	switch {
+	case errors.Is(err, moderation.ErrNotModerator):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.As(err, &configErr):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Could not process rules", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,6 @@
+package automod
+
+type Service interface {
+	GetConfig(userID int, category string) (Config, error)
+	UpdateConfig(userID int, category string, config Config) (Config, error)
+}
@@ -0,0 +1,168 @@
+package automod
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"redditclone/internal/utils"
+)
+
+// content is what a rule is matched against.
+type content struct {
+	kind            string
+	title           string
+	body            string
+	urls            []string
+	authorCreated   time.Time
+	authorKarma     int
+	reports         int
+	checkingReports bool
+}
+
+// rule is a Rule with its patterns compiled.
+type rule struct {
+	Rule
+	title []*regexp.Regexp
+	body  []*regexp.Regexp
+}
+
+// ConfigError points at the rule that failed validation.
+type ConfigError struct {
+	Rule    int
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("rule %d: %s", e.Rule+1, e.Message)
+}
+
+func compile(config Config) ([]rule, error) {
+	rules := make([]rule, 0, len(config.Rules))
+	for i, r := range config.Rules {
+		fail := func(format string, args ...interface{}) error {
+			return &ConfigError{Rule: i, Message: fmt.Sprintf(format, args...)}
+		}
+
+		switch r.Type {
+		case "":
+			r.Type = TypeAny
+		case TypePost, TypeComment, TypeAny:
+		default:
+			return nil, fail("type must be one of post, comment or any")
+		}
+		if len(r.Actions) == 0 {
+			return nil, fail("at least one action is required")
+		}
+		for _, action := range r.Actions {
+			switch action {
+			case ActionRemove, ActionFilter, ActionReply:
+			case ActionFlair, ActionLock:
+				if r.Type != TypePost {
+					return nil, fail("%s only applies to rules of type post", action)
+				}
+			default:
+				return nil, fail("unknown action %q", action)
+			}
+		}
+		if has(r.Actions, ActionFlair) && (r.Flair == nil || strings.TrimSpace(r.Flair.Text) == "") {
+			return nil, fail("flair action needs flair text")
+		}
+		if has(r.Actions, ActionReply) && strings.TrimSpace(r.Reply) == "" {
+			return nil, fail("reply action needs reply text")
+		}
+		if r.ReportsAtLeast < 0 {
+			return nil, fail("reports_at_least must not be negative")
+		}
+
+		compiled := rule{Rule: r}
+		for _, pattern := range r.Title {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fail("invalid title pattern %q: %v", pattern, err)
+			}
+			compiled.title = append(compiled.title, re)
+		}
+		for _, pattern := range r.Body {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fail("invalid body pattern %q: %v", pattern, err)
+			}
+			compiled.body = append(compiled.body, re)
+		}
+		rules = append(rules, compiled)
+	}
+	return rules, nil
+}
+
+func (r rule) matches(c content) bool {
+	if r.Type != TypeAny && r.Type != c.kind {
+		return false
+	}
+	// Rules about reports run only when a report comes in, and the others
+	// only when content is created, so no rule fires twice.
+	if c.checkingReports != (r.ReportsAtLeast > 0) {
+		return false
+	}
+	if c.checkingReports && c.reports != r.ReportsAtLeast {
+		return false
+	}
+
+	if len(r.title) > 0 && (c.kind != TypePost || !anyMatch(r.title, c.title)) {
+		return false
+	}
+	if len(r.body) > 0 && !anyMatch(r.body, c.body) {
+		return false
+	}
+	if len(r.Domains) > 0 && !anyDomain(r.Domains, c.urls) {
+		return false
+	}
+	if r.AccountAgeBelow != nil && time.Since(c.authorCreated) >= time.Duration(*r.AccountAgeBelow)*24*time.Hour {
+		return false
+	}
+	if r.KarmaBelow != nil && c.authorKarma >= *r.KarmaBelow {
+		return false
+	}
+	return true
+}
+
+func anyMatch(patterns []*regexp.Regexp, text string) bool {
+	for _, re := range patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+func anyDomain(domains []string, urls []string) bool {
+	for _, rawURL := range urls {
+		for _, domain := range domains {
+			if utils.DomainMatches(rawURL, domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// links returns the link of a link post, if any, and the URLs in text.
+func links(link, text string) []string {
+	urls := urlPattern.FindAllString(text, -1)
+	if link != "" {
+		urls = append(urls, link)
+	}
+	return urls
+}
+
+func has(actions []string, action string) bool {
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package automod
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"redditclone/internal/comment"
+	"redditclone/internal/events"
+	"redditclone/internal/karma"
+	"redditclone/internal/moderation"
+	"redditclone/internal/post"
+	"redditclone/internal/user"
+)
+
+type automodService struct {
+	mu      sync.RWMutex
+	configs map[string]Config
+	rules   map[string][]rule
+
+	botID             int
+	postService       post.Service
+	commentService    comment.Service
+	userService       user.Service
+	karmaService      karma.Service
+	moderationService moderation.Service
+	bus               *events.Bus
+	logger            *log.Logger
+}
+
+// NewAutomodService runs each category's rules as hooks on new posts and
+// comments, and on reports for rules about report counts. It acts as the
+// botID user, whose own content is never checked, and its actions appear in
+// the moderator log like anyone else's.
+func NewAutomodService(botID int, postService post.Service, commentService comment.Service, userService user.Service, karmaService karma.Service, moderationService moderation.Service, bus *events.Bus, logger *log.Logger) Service {
+	s := &automodService{
+		configs:           make(map[string]Config),
+		rules:             make(map[string][]rule),
+		botID:             botID,
+		postService:       postService,
+		commentService:    commentService,
+		userService:       userService,
+		karmaService:      karmaService,
+		moderationService: moderationService,
+		bus:               bus,
+		logger:            logger,
+	}
+
+	bus.Subscribe(events.PostCreated, s.postCreated)
+	bus.Subscribe(events.CommentCreated, s.commentCreated)
+	bus.Subscribe(events.ContentReported, s.contentReported)
+
+	return s
+}
+
+func (s *automodService) GetConfig(userID int, category string) (Config, error) {
+	if !s.moderationService.IsModerator(userID, category) {
+		return Config{}, moderation.ErrNotModerator
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	config, ok := s.configs[category]
+	if !ok {
+		return Config{Rules: []Rule{}}, nil
+	}
+	return config, nil
+}
+
+// UpdateConfig replaces the category's rules. Nothing changes unless every
+// rule is valid.
+func (s *automodService) UpdateConfig(userID int, category string, config Config) (Config, error) {
+	if !s.moderationService.IsModerator(userID, category) {
+		return Config{}, moderation.ErrNotModerator
+	}
+	if config.Rules == nil {
+		config.Rules = []Rule{}
+	}
+
+	rules, err := compile(config)
+	if err != nil {
+		return Config{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.configs[category] = config
+	s.rules[category] = rules
+	return config, nil
+}
+
+func (s *automodService) postCreated(e events.Event) {
+	p, ok := e.Payload.(post.Post)
+	if !ok || p.AuthorID == s.botID {
+		return
+	}
+
+	s.run(p.Category, p, nil, s.describe(TypePost, p.Title, p.Text, p.URL, p.AuthorID))
+}
+
+func (s *automodService) commentCreated(e events.Event) {
+	c, ok := e.Payload.(comment.Comment)
+	if !ok || c.AuthorID == s.botID {
+		return
+	}
+
+	p, err := s.postService.GetPostByID(c.PostID)
+	if err != nil {
+		return
+	}
+
+	s.run(p.Category, p, &c, s.describe(TypeComment, "", c.Text, "", c.AuthorID))
+}
+
+func (s *automodService) contentReported(e events.Event) {
+	report, ok := e.Payload.(moderation.ReportEvent)
+	if !ok || report.ReporterID == s.botID {
+		return
+	}
+
+	p, err := s.postService.GetPostByID(report.PostID)
+	if err != nil {
+		return
+	}
+
+	var (
+		c    *comment.Comment
+		item content
+	)
+	if report.CommentID != 0 {
+		found, err := s.commentService.GetComment(report.PostID, report.CommentID)
+		if err != nil {
+			return
+		}
+		c = &found
+		item = s.describe(TypeComment, "", found.Text, "", found.AuthorID)
+	} else {
+		item = s.describe(TypePost, p.Title, p.Text, p.URL, p.AuthorID)
+	}
+	item.checkingReports = true
+	item.reports = report.Count
+
+	s.run(p.Category, p, c, item)
+}
+
+func (s *automodService) describe(kind, title, body, link string, authorID int) content {
+	item := content{
+		kind:  kind,
+		title: title,
+		body:  body,
+		urls:  links(link, body),
+	}
+	if u, err := s.userService.GetUserByID(authorID); err == nil {
+		item.authorCreated = u.Created
+	}
+	postKarma, commentKarma := s.karmaService.Karma(authorID)
+	item.authorKarma = postKarma + commentKarma
+	return item
+}
+
+// run applies the actions of every matching rule to the post, or to the
+// comment when c is not nil.
+func (s *automodService) run(category string, p post.Post, c *comment.Comment, item content) {
+	s.mu.RLock()
+	rules := s.rules[category]
+	s.mu.RUnlock()
+
+	for _, r := range rules {
+		if r.matches(item) {
+			s.apply(category, r.Rule, p, c)
+		}
+	}
+}
+
+// apply carries out the rule's actions in a fixed order, so that a reply is
+// posted before the thread is locked and nothing follows a removal.
+func (s *automodService) apply(category string, r Rule, p post.Post, c *comment.Comment) {
+	reason := r.Reason
+	if reason == "" {
+		reason = r.Name
+	}
+	commentID := 0
+	if c != nil {
+		commentID = c.ID
+	}
+
+	var err error
+	for _, action := range []string{ActionFlair, ActionReply, ActionLock, ActionFilter, ActionRemove} {
+		if !has(r.Actions, action) {
+			continue
+		}
+
+		switch action {
+		case ActionFlair:
+			flair := *r.Flair
+			if _, err = s.postService.SetFlair(p.ID, &flair); err == nil {
+				s.record(category, moderation.ActionFlair, p.ID, 0, reason)
+			}
+		case ActionReply:
+			_, err = s.commentService.AddComment(p.ID, comment.Comment{
+				AuthorID: s.botID,
+				ParentID: commentID,
+				Text:     r.Reply,
+			})
+		case ActionLock:
+			if _, err = s.postService.SetLocked(p.ID, true); err == nil {
+				s.record(category, moderation.ActionLock, p.ID, 0, reason)
+			}
+		case ActionFilter:
+			if c != nil {
+				err = s.moderationService.FilterComment(s.botID, p.ID, c.ID, Username+": "+reason)
+			} else {
+				err = s.moderationService.FilterPost(s.botID, p.ID, Username+": "+reason)
+			}
+		case ActionRemove:
+			if c != nil {
+				_, err = s.commentService.RemoveComment(p.ID, c.ID)
+			} else {
+				_, err = s.postService.RemovePost(p.ID)
+			}
+			if err == nil {
+				s.record(category, moderation.ActionRemove, p.ID, commentID, reason)
+			}
+		}
+
+		if err != nil {
+			s.logger.Printf("AutoModerator rule %q in %s: %s failed: %v\n", r.Name, category, action, err)
+		}
+	}
+}
+
+func (s *automodService) record(category, action string, postID, commentID int, reason string) {
+	s.bus.Publish(events.ModeratorAction, moderation.Action{
+		Category:    category,
+		Action:      action,
+		ModeratorID: s.botID,
+		PostID:      postID,
+		CommentID:   commentID,
+		Reason:      reason,
+		Created:     time.Now(),
+	})
+}
@@ -0,0 +1,47 @@
+package ban
+
+import (
+	"fmt"
+	"time"
+)
+
+// Ban keeps a user from posting, commenting and voting in Category, or on
+// the whole site when Category is empty. A nil Expires means the ban is
+// permanent. A shadowban lets the user carry on as usual while their posts
+// and comments are hidden from everyone else.
+type Ban struct {
+	UserID      int        `json:"user_id"`
+	Username    string     `json:"username"`
+	Category    string     `json:"category,omitempty"`
+	Reason      string     `json:"reason"`
+	Shadow      bool       `json:"shadow"`
+	ModeratorID int        `json:"moderator_id"`
+	Created     time.Time  `json:"created"`
+	Expires     *time.Time `json:"expires,omitempty"`
+}
+
+func (b Ban) Active(now time.Time) bool {
+	return b.Expires == nil || now.Before(*b.Expires)
+}
+
+// BannedError is returned by the guards to banned users and explains the
+// ban.
+type BannedError struct {
+	Ban Ban
+}
+
+func (e *BannedError) Error() string {
+	where := "this site"
+	if e.Ban.Category != "" {
+		where = e.Ban.Category
+	}
+
+	msg := "you are banned from " + where
+	if e.Ban.Expires != nil {
+		msg += " until " + e.Ban.Expires.UTC().Format(time.RFC3339)
+	}
+	if e.Ban.Reason != "" {
+		msg += fmt.Sprintf(": %s", e.Ban.Reason)
+	}
+	return msg
+}
@@ -0,0 +1,126 @@
+package ban
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"redditclone/internal/moderation"
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewBanHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// BanRequest takes the duration as a Go duration string such as "72h"; an
+// empty duration bans permanently.
+type BanRequest struct {
+	Reason   string `json:"reason"`
+	Duration string `json:"duration"`
+	Shadow   bool   `json:"shadow"`
+}
+
+// The handlers serve both /api/bans and /api/mod/{category}/bans; without a
+// category in the route they manage site-wide bans.
+
+func (h *Handler) GetBans(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting bans")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	bans, err := h.service.GetBans(userID, mux.Vars(r)["category"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(bans); err != nil {
+		http.Error(w, "Failed to encode bans", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) Ban(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Banning a user")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req BanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			http.Error(w, "Invalid duration", http.StatusBadRequest)
+			return
+		}
+		duration = d
+	}
+
+	vars := mux.Vars(r)
+	b, err := h.service.Ban(userID, vars["category"], vars["userLogin"], req.Reason, duration, req.Shadow)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(b); err != nil {
+		http.Error(w, "Failed to encode ban", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) Unban(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Unbanning a user")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := h.service.Unban(userID, vars["category"], vars["userLogin"]); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotAdmin), errors.Is(err, moderation.ErrNotModerator), errors.Is(err, ErrCannotBanModerator):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrInvalidDuration), errors.Is(err, ErrReasonTooLong):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	}
+}
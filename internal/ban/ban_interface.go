@@ -0,0 +1,24 @@
+package ban
+
+import (
+	"time"
+
+	"redditclone/internal/comment"
+	"redditclone/internal/post"
+)
+
+type Service interface {
+	// Ban bans the user for duration, or permanently when it is 0. An empty
+	// category bans the user site-wide, which only admins may do.
+	Ban(moderatorID int, category, username, reason string, duration time.Duration, shadow bool) (Ban, error)
+	Unban(moderatorID int, category, username string) error
+	GetBans(moderatorID int, category string) ([]Ban, error)
+	ActiveBan(userID int, category string) (Ban, bool)
+	PostGuard(action string, p post.Post, userID int) error
+	CommentGuard(action string, c comment.Comment, userID int) error
+	HideShadowbannedPosts(viewerID int, posts []post.Post) []post.Post
+	HideShadowbannedComments(viewerID int, p post.Post, comments []comment.Comment) []comment.Comment
+	// HideShadowbannedUserComments filters comments from any number of
+	// posts, such as a user's comments, looking up each comment's category.
+	HideShadowbannedUserComments(viewerID int, comments []comment.Comment) []comment.Comment
+}
@@ -0,0 +1,285 @@
+package ban
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"redditclone/internal/comment"
+	"redditclone/internal/events"
+	"redditclone/internal/moderation"
+	"redditclone/internal/post"
+	"redditclone/internal/user"
+)
+
+const (
+	maxReasonLength = 500
+
+	ActionBan   = "ban"
+	ActionUnban = "unban"
+)
+
+var (
+	ErrNotAdmin           = errors.New("only administrators can manage site-wide bans")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrNotBanned          = errors.New("user is not banned")
+	ErrCannotBanModerator = errors.New("moderators cannot be banned")
+	ErrInvalidDuration    = errors.New("duration must not be negative")
+	ErrReasonTooLong      = errors.New("reason must be at most 500 characters")
+)
+
+type banService struct {
+	mu                sync.RWMutex
+	bans              map[string]map[int]Ban
+	moderationService moderation.Service
+	userService       user.Service
+	postService       post.Service
+	bus               *events.Bus
+}
+
+// NewBanService enforces bans through post and comment guards and hides
+// shadowbanned content through the listing filters. Bans and unbans are
+// published as ModeratorAction, site-wide ones under the empty category and
+// shadowbans as private actions.
+func NewBanService(moderationService moderation.Service, userService user.Service, postService post.Service, bus *events.Bus) Service {
+	return &banService{
+		bans:              make(map[string]map[int]Ban),
+		moderationService: moderationService,
+		userService:       userService,
+		postService:       postService,
+		bus:               bus,
+	}
+}
+
+func (s *banService) Ban(moderatorID int, category, username, reason string, duration time.Duration, shadow bool) (Ban, error) {
+	if err := s.authorize(moderatorID, category); err != nil {
+		return Ban{}, err
+	}
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > maxReasonLength {
+		return Ban{}, ErrReasonTooLong
+	}
+	if duration < 0 {
+		return Ban{}, ErrInvalidDuration
+	}
+
+	u, err := s.userService.GetUserByUsername(username)
+	if err != nil {
+		return Ban{}, ErrUserNotFound
+	}
+	if s.moderationService.IsModerator(u.ID, category) {
+		return Ban{}, ErrCannotBanModerator
+	}
+
+	b := Ban{
+		UserID:      u.ID,
+		Username:    u.Username,
+		Category:    category,
+		Reason:      reason,
+		Shadow:      shadow,
+		ModeratorID: moderatorID,
+		Created:     time.Now(),
+	}
+	if duration > 0 {
+		expires := b.Created.Add(duration)
+		b.Expires = &expires
+	}
+
+	s.mu.Lock()
+	if s.bans[category] == nil {
+		s.bans[category] = make(map[int]Ban)
+	}
+	s.bans[category][u.ID] = b
+	s.mu.Unlock()
+
+	s.bus.Publish(events.ModeratorAction, moderation.Action{
+		Category:     category,
+		Action:       ActionBan,
+		ModeratorID:  moderatorID,
+		TargetUserID: u.ID,
+		Reason:       reason,
+		Private:      shadow,
+		Created:      b.Created,
+	})
+	return b, nil
+}
+
+func (s *banService) Unban(moderatorID int, category, username string) error {
+	if err := s.authorize(moderatorID, category); err != nil {
+		return err
+	}
+
+	u, err := s.userService.GetUserByUsername(username)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	s.mu.Lock()
+	b, ok := s.bans[category][u.ID]
+	if !ok || !b.Active(time.Now()) {
+		s.mu.Unlock()
+		return ErrNotBanned
+	}
+	delete(s.bans[category], u.ID)
+	s.mu.Unlock()
+
+	s.bus.Publish(events.ModeratorAction, moderation.Action{
+		Category:     category,
+		Action:       ActionUnban,
+		ModeratorID:  moderatorID,
+		TargetUserID: u.ID,
+		Private:      b.Shadow,
+		Created:      time.Now(),
+	})
+	return nil
+}
+
+// GetBans returns the category's active bans, newest first.
+func (s *banService) GetBans(moderatorID int, category string) ([]Ban, error) {
+	if err := s.authorize(moderatorID, category); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	bans := []Ban{}
+	for _, b := range s.bans[category] {
+		if b.Active(now) {
+			bans = append(bans, b)
+		}
+	}
+
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Created.After(bans[j].Created) })
+	return bans, nil
+}
+
+// ActiveBan returns the most restrictive of the user's bans that apply in
+// category: a ban the user notices wins over a shadowban, and a site-wide ban
+// over the category's ban of the same kind. Expired bans are ignored.
+func (s *banService) ActiveBan(userID int, category string) (Ban, bool) {
+	bans := s.activeBans(userID, category)
+	if len(bans) == 0 {
+		return Ban{}, false
+	}
+	for _, b := range bans {
+		if !b.Shadow {
+			return b, true
+		}
+	}
+	return bans[0], true
+}
+
+// activeBans returns the user's site-wide ban and the category's ban, in that
+// order, leaving out those that do not exist or have expired.
+func (s *banService) activeBans(userID int, category string) []Ban {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var bans []Ban
+	if b, ok := s.bans[""][userID]; ok && b.Active(now) {
+		bans = append(bans, b)
+	}
+	if category == "" {
+		return bans
+	}
+	if b, ok := s.bans[category][userID]; ok && b.Active(now) {
+		bans = append(bans, b)
+	}
+	return bans
+}
+
+// PostGuard rejects posts and votes by users under any ban they would notice.
+// Users who are only shadowbanned are let through so they do not notice.
+func (s *banService) PostGuard(action string, p post.Post, userID int) error {
+	if b, ok := s.ActiveBan(userID, p.Category); ok && !b.Shadow {
+		return &BannedError{Ban: b}
+	}
+	return nil
+}
+
+func (s *banService) CommentGuard(action string, c comment.Comment, userID int) error {
+	category := ""
+	if p, err := s.postService.GetPostByID(c.PostID); err == nil {
+		category = p.Category
+	}
+
+	if b, ok := s.ActiveBan(userID, category); ok && !b.Shadow {
+		return &BannedError{Ban: b}
+	}
+	return nil
+}
+
+// HideShadowbannedPosts drops posts by shadowbanned authors, except for the
+// author and the moderators of the post's category.
+func (s *banService) HideShadowbannedPosts(viewerID int, posts []post.Post) []post.Post {
+	visible := make([]post.Post, 0, len(posts))
+	for _, p := range posts {
+		if s.hidden(viewerID, p.AuthorID, p.Category) {
+			continue
+		}
+		visible = append(visible, p)
+	}
+	return visible
+}
+
+func (s *banService) HideShadowbannedComments(viewerID int, p post.Post, comments []comment.Comment) []comment.Comment {
+	visible := make([]comment.Comment, 0, len(comments))
+	for _, c := range comments {
+		if s.hidden(viewerID, c.AuthorID, p.Category) {
+			continue
+		}
+		visible = append(visible, c)
+	}
+	return visible
+}
+
+func (s *banService) HideShadowbannedUserComments(viewerID int, comments []comment.Comment) []comment.Comment {
+	visible := make([]comment.Comment, 0, len(comments))
+	for _, c := range comments {
+		// Comments under deleted posts are checked against site-wide bans.
+		var category string
+		if p, err := s.postService.GetPostByID(c.PostID); err == nil {
+			category = p.Category
+		}
+		if s.hidden(viewerID, c.AuthorID, category) {
+			continue
+		}
+		visible = append(visible, c)
+	}
+	return visible
+}
+
+func (s *banService) hidden(viewerID, authorID int, category string) bool {
+	if authorID == 0 || authorID == viewerID {
+		return false
+	}
+	shadowbanned := false
+	for _, b := range s.activeBans(authorID, category) {
+		shadowbanned = shadowbanned || b.Shadow
+	}
+	if !shadowbanned {
+		return false
+	}
+	return viewerID == 0 || !s.moderationService.IsModerator(viewerID, category)
+}
+
+// authorize lets category moderators manage their category's bans and only
+// admins manage site-wide ones.
+func (s *banService) authorize(moderatorID int, category string) error {
+	if category == "" {
+		if !s.moderationService.IsAdmin(moderatorID) {
+			return ErrNotAdmin
+		}
+		return nil
+	}
+	if !s.moderationService.IsModerator(moderatorID, category) {
+		return moderation.ErrNotModerator
+	}
+	return nil
+}
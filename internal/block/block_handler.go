@@ -0,0 +1,77 @@
+package block
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewBlockHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) GetBlocked(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting blocked users")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	blocked, err := h.service.GetBlocked(userID)
+	if err != nil {
+		http.Error(w, "Could not retrieve blocked users", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(blocked); err != nil {
+		http.Error(w, "Failed to encode blocked users", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) Block(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Blocking a user")
+	h.handle(w, r, h.service.Block)
+}
+
+func (h *Handler) Unblock(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Unblocking a user")
+	h.handle(w, r, h.service.Unblock)
+}
+
+func (h *Handler) handle(w http.ResponseWriter, r *http.Request, action func(userID int, username string) error) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := action(userID, mux.Vars(r)["userLogin"])
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrBlockSelf):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrAlreadyBlocked), errors.Is(err, ErrNotBlocked):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "User not found", http.StatusNotFound)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
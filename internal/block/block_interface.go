@@ -0,0 +1,17 @@
+package block
+
+import (
+	"redditclone/internal/comment"
+	"redditclone/internal/post"
+)
+
+type Service interface {
+	Block(userID int, username string) error
+	Unblock(userID int, username string) error
+	GetBlocked(userID int) ([]string, error)
+	HasBlocked(userID, otherID int) bool
+	CollapsePosts(viewerID int, posts []post.Post) []post.Post
+	CollapseComments(viewerID int, p post.Post, comments []comment.Comment) []comment.Comment
+	PostGuard(action string, p post.Post, userID int) error
+	CommentGuard(action string, c comment.Comment, userID int) error
+}
@@ -0,0 +1,160 @@
+package block
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"redditclone/internal/comment"
+	"redditclone/internal/post"
+	"redditclone/internal/user"
+)
+
+var (
+	ErrBlockSelf      = errors.New("cannot block yourself")
+	ErrAlreadyBlocked = errors.New("user is already blocked")
+	ErrNotBlocked     = errors.New("user is not blocked")
+	ErrReplyBlocked   = errors.New("you cannot reply to this user's content")
+	ErrVoteBlocked    = errors.New("you cannot vote on this user's content")
+)
+
+type blockService struct {
+	mu             sync.RWMutex
+	blocked        map[int]map[int]bool
+	userService    user.Service
+	postService    post.Service
+	commentService comment.Service
+}
+
+func NewBlockService(userService user.Service, postService post.Service, commentService comment.Service) Service {
+	return &blockService{
+		blocked:        make(map[int]map[int]bool),
+		userService:    userService,
+		postService:    postService,
+		commentService: commentService,
+	}
+}
+
+func (s *blockService) Block(userID int, username string) error {
+	target, err := s.userService.GetUserByUsername(username)
+	if err != nil {
+		return err
+	}
+	if target.ID == userID {
+		return ErrBlockSelf
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.blocked[userID][target.ID] {
+		return ErrAlreadyBlocked
+	}
+	if s.blocked[userID] == nil {
+		s.blocked[userID] = make(map[int]bool)
+	}
+	s.blocked[userID][target.ID] = true
+	return nil
+}
+
+func (s *blockService) Unblock(userID int, username string) error {
+	target, err := s.userService.GetUserByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.blocked[userID][target.ID] {
+		return ErrNotBlocked
+	}
+	delete(s.blocked[userID], target.ID)
+	return nil
+}
+
+func (s *blockService) GetBlocked(userID int) ([]string, error) {
+	s.mu.RLock()
+	ids := make([]int, 0, len(s.blocked[userID]))
+	for id := range s.blocked[userID] {
+		ids = append(ids, id)
+	}
+	s.mu.RUnlock()
+
+	usernames := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if u, err := s.userService.GetUserByID(id); err == nil {
+			usernames = append(usernames, u.Username)
+		}
+	}
+	sort.Strings(usernames)
+	return usernames, nil
+}
+
+// HasBlocked reports whether userID has blocked otherID.
+func (s *blockService) HasBlocked(userID, otherID int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.blocked[userID][otherID]
+}
+
+// CollapsePosts is a post.ListFilter that marks posts by users the viewer
+// has blocked as collapsed.
+func (s *blockService) CollapsePosts(viewerID int, posts []post.Post) []post.Post {
+	if viewerID == 0 {
+		return posts
+	}
+
+	result := make([]post.Post, len(posts))
+	for i, p := range posts {
+		p.Collapsed = p.Collapsed || s.HasBlocked(viewerID, p.AuthorID)
+		result[i] = p
+	}
+	return result
+}
+
+// CollapseComments is a post.CommentFilter doing the same for comments.
+func (s *blockService) CollapseComments(viewerID int, _ post.Post, comments []comment.Comment) []comment.Comment {
+	if viewerID == 0 {
+		return comments
+	}
+
+	result := make([]comment.Comment, len(comments))
+	for i, c := range comments {
+		c.Collapsed = c.Collapsed || (c.AuthorID != 0 && s.HasBlocked(viewerID, c.AuthorID))
+		result[i] = c
+	}
+	return result
+}
+
+// PostGuard stops blocked users from voting on the blocker's posts.
+func (s *blockService) PostGuard(action string, p post.Post, userID int) error {
+	if action == post.ActionVote && s.HasBlocked(p.AuthorID, userID) {
+		return ErrVoteBlocked
+	}
+	return nil
+}
+
+// CommentGuard stops blocked users from commenting on the blocker's posts,
+// replying to the blocker's comments and voting on them.
+func (s *blockService) CommentGuard(action string, c comment.Comment, userID int) error {
+	if action == comment.ActionVote {
+		if s.HasBlocked(c.AuthorID, userID) {
+			return ErrVoteBlocked
+		}
+		return nil
+	}
+
+	if p, err := s.postService.GetPostByID(c.PostID); err == nil && s.HasBlocked(p.AuthorID, userID) {
+		return ErrReplyBlocked
+	}
+
+	if c.ParentID != 0 {
+		parent, err := s.commentService.GetComment(c.PostID, c.ParentID)
+		if err == nil && s.HasBlocked(parent.AuthorID, userID) {
+			return ErrReplyBlocked
+		}
+	}
+	return nil
+}
@@ -1,8 +1,77 @@
 package comment
 
+import "time"
+
+// DeletedText and RemovedText replace the body of a comment deleted by its
+// author or removed by a moderator inside its thread.
+const (
+	DeletedText = "[deleted]"
+	RemovedText = "[removed]"
+)
+
 type Comment struct {
-	ID       int    `json:"id"`
-	PostID   int    `json:"post_id"`
-	AuthorID int    `json:"author_id"`
-	Text     string `json:"text"`
+	ID        int         `json:"id"`
+	PostID    int         `json:"post_id"`
+	ParentID  int         `json:"parent_id,omitempty"`
+	AuthorID  int         `json:"author_id"`
+	Text      string      `json:"text"`
+	Upvotes   int         `json:"upvotes"`
+	Downvotes int         `json:"downvotes"`
+	Voters    map[int]int `json:"-"`
+	Created   time.Time   `json:"created"`
+	Version   int         `json:"version"`
+	Collapsed bool        `json:"collapsed,omitempty"`
+	Deleted   bool        `json:"deleted,omitempty"`
+	DeletedAt time.Time   `json:"-"`
+	Removed   bool        `json:"removed,omitempty"`
+}
+
+// Score is the comment's rating: upvotes minus downvotes.
+func (c Comment) Score() int {
+	return c.Upvotes - c.Downvotes
+}
+
+// VoteEvent is the payload of events.CommentVoted. Previous and Vote are the
+// voter's old and new vote: 1, -1, or 0 for none.
+type VoteEvent struct {
+	Comment  Comment
+	UserID   int
+	Previous int
+	Vote     int
+}
+
+// Actions passed to a Guard.
+const (
+	ActionCreate = "create"
+	ActionVote   = "vote"
+)
+
+// Guard vets an action by userID on c before the service applies it; a
+// non-nil error rejects the action. For ActionCreate c is the comment about
+// to be added.
+type Guard func(action string, c Comment, userID int) error
+
+// ListFilter drops the comments a viewer should not see from a listing
+// that is not tied to one post, such as a user's comments. viewerID is 0 for
+// anonymous requests.
+type ListFilter func(viewerID int, comments []Comment) []Comment
+
+// Posts is how the service learns whether a comment's post is still there.
+// It is kept this small so that the post service, which depends on this
+// package, can provide it.
+type Posts interface {
+	PostExists(id int) bool
+}
+
+// RejectedError wraps the error of the Guard that refused an action.
+type RejectedError struct {
+	Err error
+}
+
+func (e *RejectedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RejectedError) Unwrap() error {
+	return e.Err
 }
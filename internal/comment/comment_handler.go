@@ -2,27 +2,41 @@ package comment
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
+
+	"redditclone/internal/user"
+	"redditclone/internal/utils"
 )
 
 type Handler struct {
-	service Service
-	logger  *log.Logger
+	service     Service
+	userService user.Service
+	listFilters []ListFilter
+	logger      *log.Logger
 }
 
-func NewCommentHandler(service Service, logger *log.Logger) *Handler {
+func NewCommentHandler(service Service, userService user.Service, logger *log.Logger) *Handler {
 	return &Handler{
-		service: service,
-		logger:  logger,
+		service:     service,
+		userService: userService,
+		logger:      logger,
 	}
 }
 
+// AddListFilter appends a step run over a user's comments before they are
+// paginated.
+func (h *Handler) AddListFilter(filter ListFilter) {
+	h.listFilters = append(h.listFilters, filter)
+}
+
 type AddCommentRequest struct {
-	Text string `json:"text"`
+	Text     string `json:"text"`
+	ParentID int    `json:"parent_id,omitempty"`
 }
 
 func (h *Handler) AddComment(w http.ResponseWriter, r *http.Request) {
@@ -48,15 +62,32 @@ func (h *Handler) AddComment(w http.ResponseWriter, r *http.Request) {
 
 	comment := Comment{
 		Text:     req.Text,
+		ParentID: req.ParentID,
 		AuthorID: userID,
 	}
 
 	createdComment, err := h.service.AddComment(postID, comment)
 	if err != nil {
+		if errors.Is(err, ErrParentNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, ErrPostNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
 		http.Error(w, "Could not add comment", http.StatusBadRequest)
 		return
 	}
 
+	w.Header().Set("ETag", utils.ETag(createdComment.Version))
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(createdComment); err != nil {
 		http.Error(w, "Failed to encode comment", http.StatusInternalServerError)
@@ -87,11 +118,165 @@ func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.DeleteComment(postID, commentID, userID)
+	version, err := utils.IfMatchVersion(r)
+	if err != nil {
+		utils.WritePreconditionError(w, err)
+		return
+	}
+
+	err = h.service.DeleteComment(postID, commentID, userID, version)
 	if err != nil {
+		if errors.Is(err, ErrVersionConflict) {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *Handler) RestoreComment(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Restoring comment")
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	postID, err := strconv.Atoi(vars["postID"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(vars["commentID"])
+	if err != nil {
+		http.Error(w, "Invalid IDs", http.StatusBadRequest)
+		return
+	}
+
+	version, err := utils.IfMatchVersion(r)
+	if err != nil {
+		utils.WritePreconditionError(w, err)
+		return
+	}
+
+	err = h.service.RestoreComment(postID, commentID, userID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrVersionConflict):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		case errors.Is(err, ErrRestoreNotOwner), errors.Is(err, ErrRemoved):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, ErrRestoreExpired):
+			http.Error(w, err.Error(), http.StatusGone)
+		case errors.Is(err, ErrNotDeleted):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UpvoteComment(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Upvoting a comment")
+	h.vote(w, r, h.service.UpvoteComment)
+}
+
+func (h *Handler) DownvoteComment(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Downvoting a comment")
+	h.vote(w, r, h.service.DownvoteComment)
+}
+
+func (h *Handler) UnvoteComment(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Unvoting a comment")
+	h.vote(w, r, h.service.UnvoteComment)
+}
+
+func (h *Handler) vote(w http.ResponseWriter, r *http.Request, action func(postID, commentID, userID, version int) (Comment, error)) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	postID, err := strconv.Atoi(vars["postID"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(vars["commentID"])
+	if err != nil {
+		http.Error(w, "Invalid IDs", http.StatusBadRequest)
+		return
+	}
+
+	version, err := utils.IfMatchVersion(r)
+	if err != nil {
+		utils.WritePreconditionError(w, err)
+		return
+	}
+
+	comment, err := action(postID, commentID, userID, version)
+	if err != nil {
+		var rejected *RejectedError
+		switch {
+		case errors.Is(err, ErrPostNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.As(err, &rejected):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, ErrVersionConflict):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(comment.Version))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) GetCommentsByUser(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting comments by user")
+
+	user, err := h.userService.GetUserByUsername(mux.Vars(r)["userLogin"])
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	page, err := utils.ParsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comments, err := h.service.GetCommentsByUser(user.ID)
+	if err != nil {
+		http.Error(w, "Could not retrieve comments", http.StatusInternalServerError)
+		return
+	}
+
+	viewerID, _ := r.Context().Value("userID").(int)
+	for _, filter := range h.listFilters {
+		comments = filter(viewerID, comments)
+	}
+
+	start, end := page.Bounds(len(comments))
+	utils.SetTotal(w, len(comments))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(comments[start:end]); err != nil {
+		http.Error(w, "Failed to encode comments", http.StatusInternalServerError)
+		return
+	}
+}
@@ -6,6 +6,16 @@ type Repository interface {
 }
 
 type Service interface {
+	AddGuard(guard Guard)
 	AddComment(postID int, comment Comment) (Comment, error)
-	DeleteComment(postID, commentID, userID int) error
+	GetComment(postID, commentID int) (Comment, error)
+	GetCommentsByPost(postID int) ([]Comment, error)
+	GetCommentsByUser(userID int) ([]Comment, error)
+	DeleteComment(postID, commentID, userID, version int) error
+	RestoreComment(postID, commentID, userID, version int) error
+	RemoveComment(postID, commentID int) (Comment, error)
+	PurgeDeleted(postIDs ...int) int
+	UpvoteComment(postID, commentID, userID, version int) (Comment, error)
+	DownvoteComment(postID, commentID, userID, version int) (Comment, error)
+	UnvoteComment(postID, commentID, userID, version int) (Comment, error)
 }
@@ -4,52 +4,385 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
+
+	"redditclone/internal/events"
+	"redditclone/internal/utils"
+)
+
+var (
+	ErrNotAuthorized   = errors.New("not authorized to delete this comment")
+	ErrParentNotFound  = errors.New("parent comment not found")
+	ErrNotDeleted      = errors.New("comment is not deleted")
+	ErrRestoreExpired  = errors.New("restore window has expired")
+	ErrRestoreNotOwner = errors.New("not authorized to restore this comment")
+	ErrVersionConflict = errors.New("comment was modified concurrently")
+	ErrRemoved         = errors.New("comment was removed by a moderator")
+	ErrPostNotFound    = errors.New("post not found")
 )
 
 type commentService struct {
-	mu        sync.Mutex
-	comments  []Comment
-	commentID int
+	mu            sync.Mutex
+	comments      []Comment
+	commentID     int
+	restoreWindow time.Duration
+	retention     time.Duration
+	guards        []Guard
+	posts         Posts
+	bus           *events.Bus
 }
 
-func NewCommentService() Service {
+// NewCommentService keeps deleted comments as tombstones: their authors may
+// restore them during restoreWindow, and PurgeDeleted drops them for good once
+// retention has passed. Comments are only added to, and voted on under,
+// posts that posts reports as existing.
+func NewCommentService(posts Posts, bus *events.Bus, restoreWindow, retention time.Duration) Service {
 	return &commentService{
-		commentID: 1,
-		comments:  []Comment{},
+		commentID:     1,
+		comments:      []Comment{},
+		restoreWindow: restoreWindow,
+		retention:     retention,
+		posts:         posts,
+		bus:           bus,
 	}
 }
 
+// AddGuard registers a check run before comments are added. Guards must be
+// added before the service starts handling requests.
+func (s *commentService) AddGuard(guard Guard) {
+	s.guards = append(s.guards, guard)
+}
+
 func (s *commentService) AddComment(postID int, comment Comment) (Comment, error) {
+	comment.PostID = postID
+	if err := s.check(ActionCreate, comment, comment.AuthorID); err != nil {
+		return Comment{}, err
+	}
+
 	s.mu.Lock()
-	defer s.mu.Unlock()
+
+	if comment.ParentID != 0 {
+		index := s.find(postID, comment.ParentID)
+		if index == -1 || s.comments[index].Deleted {
+			s.mu.Unlock()
+			return Comment{}, ErrParentNotFound
+		}
+	}
 
 	comment.ID = s.commentID
 	comment.PostID = postID
+	comment.Voters = make(map[int]int)
+	comment.Version = 1
+	comment.Created = time.Now()
 	s.commentID++
 	s.comments = append(s.comments, comment)
+	s.mu.Unlock()
 
+	s.bus.Publish(events.CommentCreated, comment)
 	return comment, nil
 }
 
-func (s *commentService) DeleteComment(postID, commentID, userID int) error {
+func (s *commentService) GetComment(postID, commentID int) (Comment, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	index := -1
-	for i, c := range s.comments {
-		if c.PostID == postID && c.ID == commentID {
-			if c.AuthorID != userID {
-				return errors.New("not authorized to delete this comment")
+	index := s.find(postID, commentID)
+	if index == -1 || s.comments[index].Deleted {
+		return Comment{}, fmt.Errorf("comment with ID %d not found", commentID)
+	}
+
+	return s.comments[index], nil
+}
+
+func (s *commentService) GetCommentsByPost(postID int) ([]Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comments := []Comment{}
+	for _, c := range s.comments {
+		if c.PostID != postID {
+			continue
+		}
+		if c.Deleted {
+			c.AuthorID = 0
+			c.Text = DeletedText
+			if c.Removed {
+				c.Text = RemovedText
 			}
-			index = i
-			break
+		}
+		comments = append(comments, c)
+	}
+
+	return comments, nil
+}
+
+// GetCommentsByUser returns the user's live comments, newest first.
+func (s *commentService) GetCommentsByUser(userID int) ([]Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comments := []Comment{}
+	for i := len(s.comments) - 1; i >= 0; i-- {
+		c := s.comments[i]
+		if c.AuthorID == userID && !c.Deleted {
+			comments = append(comments, c)
 		}
 	}
 
+	return comments, nil
+}
+
+func (s *commentService) DeleteComment(postID, commentID, userID, version int) error {
+	c, err := s.update(postID, commentID, func(c *Comment) error {
+		if c.Deleted {
+			return fmt.Errorf("comment with ID %d not found", commentID)
+		}
+		if c.AuthorID != userID {
+			return ErrNotAuthorized
+		}
+		if !versionMatches(*c, version) {
+			return ErrVersionConflict
+		}
+
+		c.Deleted = true
+		c.DeletedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.bus.Publish(events.CommentDeleted, c)
+	return nil
+}
+
+func (s *commentService) RestoreComment(postID, commentID, userID, version int) error {
+	c, err := s.update(postID, commentID, func(c *Comment) error {
+		if !c.Deleted {
+			return ErrNotDeleted
+		}
+		if c.AuthorID != userID {
+			return ErrRestoreNotOwner
+		}
+		if c.Removed {
+			return ErrRemoved
+		}
+		if time.Since(c.DeletedAt) > s.restoreWindow {
+			return ErrRestoreExpired
+		}
+		if !versionMatches(*c, version) {
+			return ErrVersionConflict
+		}
+
+		c.Deleted = false
+		c.DeletedAt = time.Time{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.bus.Publish(events.CommentRestored, c)
+	return nil
+}
+
+// RemoveComment soft-deletes a comment on behalf of a moderator. It stays in
+// its thread as "[removed]" and cannot be restored by its author.
+func (s *commentService) RemoveComment(postID, commentID int) (Comment, error) {
+	c, err := s.update(postID, commentID, func(c *Comment) error {
+		if c.Deleted {
+			return fmt.Errorf("comment with ID %d not found", commentID)
+		}
+
+		c.Deleted = true
+		c.DeletedAt = time.Now()
+		c.Removed = true
+		return nil
+	})
+	if err != nil {
+		return Comment{}, err
+	}
+
+	s.bus.Publish(events.CommentDeleted, c)
+	return c, nil
+}
+
+func (s *commentService) UpvoteComment(postID, commentID, userID, version int) (Comment, error) {
+	return s.vote(postID, commentID, userID, version, 1)
+}
+
+func (s *commentService) DownvoteComment(postID, commentID, userID, version int) (Comment, error) {
+	return s.vote(postID, commentID, userID, version, -1)
+}
+
+func (s *commentService) UnvoteComment(postID, commentID, userID, version int) (Comment, error) {
+	return s.vote(postID, commentID, userID, version, 0)
+}
+
+// vote replaces userID's vote on the comment with value (1, -1, or 0 to
+// remove it) if the comment is still at the expected version.
+func (s *commentService) vote(postID, commentID, userID, version, value int) (Comment, error) {
+	target, err := s.GetComment(postID, commentID)
+	if err != nil {
+		return Comment{}, err
+	}
+	if err := s.check(ActionVote, target, userID); err != nil {
+		return Comment{}, err
+	}
+
+	var previous int
+	c, err := s.update(postID, commentID, func(c *Comment) error {
+		if c.Deleted {
+			return fmt.Errorf("comment with ID %d not found", commentID)
+		}
+
+		current, voted := c.Voters[userID]
+		switch {
+		case value == 1 && current == 1:
+			return errors.New("already upvoted")
+		case value == -1 && current == -1:
+			return errors.New("already downvoted")
+		case value == 0 && !voted:
+			return errors.New("no vote to remove")
+		}
+
+		if !versionMatches(*c, version) {
+			return ErrVersionConflict
+		}
+
+		// Comments handed out by the service share the Voters map, so it
+		// is copied rather than mutated in place.
+		voters := make(map[int]int, len(c.Voters)+1)
+		for id, v := range c.Voters {
+			voters[id] = v
+		}
+
+		switch current {
+		case 1:
+			c.Upvotes--
+		case -1:
+			c.Downvotes--
+		}
+		switch value {
+		case 1:
+			c.Upvotes++
+			voters[userID] = value
+		case -1:
+			c.Downvotes++
+			voters[userID] = value
+		default:
+			delete(voters, userID)
+		}
+
+		c.Voters = voters
+		previous = current
+		return nil
+	})
+	if err != nil {
+		return Comment{}, err
+	}
+
+	s.bus.Publish(events.CommentVoted, VoteEvent{
+		Comment:  c,
+		UserID:   userID,
+		Previous: previous,
+		Vote:     value,
+	})
+	return c, nil
+}
+
+// PurgeDeleted permanently removes tombstones older than the retention period
+// together with every comment that belongs to one of the purged postIDs.
+// Tombstones that still have replies are kept so the thread stays intact.
+func (s *commentService) PurgeDeleted(postIDs ...int) int {
+	s.mu.Lock()
+
+	purgedPosts := make(map[int]bool, len(postIDs))
+	for _, id := range postIDs {
+		purgedPosts[id] = true
+	}
+
+	// Replies always come after their parent, so walking backwards sees
+	// every surviving child before the parent is considered.
+	cutoff := time.Now().Add(-s.retention)
+	hasReplies := make(map[int]bool)
+	purge := make([]bool, len(s.comments))
+	for i := len(s.comments) - 1; i >= 0; i-- {
+		c := s.comments[i]
+		expired := c.Deleted && c.DeletedAt.Before(cutoff) && !hasReplies[c.ID]
+		if purgedPosts[c.PostID] || expired {
+			purge[i] = true
+			continue
+		}
+		if c.ParentID != 0 {
+			hasReplies[c.ParentID] = true
+		}
+	}
+
+	kept := make([]Comment, 0, len(s.comments))
+	purged := []Comment{}
+	for i, c := range s.comments {
+		if purge[i] {
+			purged = append(purged, c)
+		} else {
+			kept = append(kept, c)
+		}
+	}
+	s.comments = kept
+	s.mu.Unlock()
+
+	for _, c := range purged {
+		s.bus.Publish(events.CommentPurged, c)
+	}
+	return len(purged)
+}
+
+// update applies fn to the comment under the service lock and bumps its
+// version if fn succeeds. The updated comment is returned so that events can
+// be published after the lock is released.
+func (s *commentService) update(postID, commentID int, fn func(c *Comment) error) (Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := s.find(postID, commentID)
 	if index == -1 {
-		return fmt.Errorf("comment with ID %d not found", commentID)
+		return Comment{}, fmt.Errorf("comment with ID %d not found", commentID)
+	}
+
+	c := s.comments[index]
+	if err := fn(&c); err != nil {
+		return Comment{}, err
 	}
 
-	s.comments = append(s.comments[:index], s.comments[index+1:]...)
+	c.Version++
+	s.comments[index] = c
+	return c, nil
+}
+
+// check refuses comments on missing or deleted posts, then runs the guards
+// outside of the service lock, so they are free to query this and other
+// services.
+func (s *commentService) check(action string, c Comment, userID int) error {
+	if !s.posts.PostExists(c.PostID) {
+		return ErrPostNotFound
+	}
+	for _, guard := range s.guards {
+		if err := guard(action, c, userID); err != nil {
+			return &RejectedError{Err: err}
+		}
+	}
 	return nil
 }
+
+func (s *commentService) find(postID, commentID int) int {
+	for i, c := range s.comments {
+		if c.PostID == postID && c.ID == commentID {
+			return i
+		}
+	}
+	return -1
+}
+
+func versionMatches(c Comment, version int) bool {
+	return version == utils.AnyVersion || version == c.Version
+}
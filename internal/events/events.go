@@ -0,0 +1,33 @@
+package events
+
+const (
+	UserRegistered = "user.registered"
+
+	PostCreated  = "post.created"
+	PostDeleted  = "post.deleted"
+	PostRestored = "post.restored"
+	PostPurged   = "post.purged"
+	PostVoted    = "post.voted"
+
+	CommentCreated  = "comment.created"
+	CommentDeleted  = "comment.deleted"
+	CommentRestored = "comment.restored"
+	CommentPurged   = "comment.purged"
+	CommentVoted    = "comment.voted"
+
+	NotificationCreated = "notification.created"
+	LiveThreadUpdated   = "live.updated"
+	MessageSent         = "message.sent"
+
+	ModeratorAction = "moderator.action"
+	ContentReported = "content.reported"
+)
+
+// Event is delivered to subscribers of its Type. Payload is the value the
+// publishing service documents for that type, e.g. post.Post for PostCreated.
+type Event struct {
+	Type    string
+	Payload interface{}
+}
+
+type Handler func(Event)
@@ -0,0 +1,47 @@
+package events
+
+import "sync"
+
+// Bus is an in-process publish/subscribe hub. Handlers run synchronously in
+// the publisher's goroutine, after the publishing service has released its
+// locks, so they may call back into any service. Asynchronous subscriptions
+// are offered each event without blocking once the handlers have run, so
+// consumers see their effects, such as content held by the AutoModerator;
+// see SubscribeAsync.
+type Bus struct {
+	mu            sync.RWMutex
+	handlers      map[string][]Handler
+	subscriptions map[*Subscription]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		handlers:      make(map[string][]Handler),
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+func (b *Bus) Publish(eventType string, payload interface{}) {
+	event := Event{Type: eventType, Payload: payload}
+
+	b.mu.RLock()
+	handlers := b.handlers[eventType]
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+
+	b.mu.RLock()
+	for s := range b.subscriptions {
+		s.offer(event)
+	}
+	b.mu.RUnlock()
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Subscription receives events on a buffered channel instead of running a
+// handler in the publisher's goroutine. It is meant for consumers such as
+// network streams that may fall behind: when the buffer is full the event is
+// dropped for this subscriber and counted, so a slow client never holds up
+// the services publishing events.
+type Subscription struct {
+	C <-chan Event
+
+	ch      chan Event
+	types   map[string]bool
+	dropped atomic.Int64
+	bus     *Bus
+	once    sync.Once
+}
+
+// SubscribeAsync delivers events of the given types to a new subscription
+// with room for buffer undelivered events. The subscription must be closed
+// when the consumer is done.
+func (b *Bus) SubscribeAsync(buffer int, eventTypes ...string) *Subscription {
+	ch := make(chan Event, buffer)
+	s := &Subscription{
+		C:     ch,
+		ch:    ch,
+		types: make(map[string]bool, len(eventTypes)),
+		bus:   b,
+	}
+	for _, t := range eventTypes {
+		s.types[t] = true
+	}
+
+	b.mu.Lock()
+	b.subscriptions[s] = struct{}{}
+	b.mu.Unlock()
+
+	return s
+}
+
+// Dropped returns the number of events dropped since the last call.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// Close detaches the subscription from the bus and closes C.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subscriptions, s)
+		s.bus.mu.Unlock()
+
+		close(s.ch)
+	})
+}
+
+// offer hands e to the subscriber without blocking. The bus read lock is
+// held by the caller, so the channel cannot be closed concurrently.
+func (s *Subscription) offer(e Event) {
+	if !s.types[e.Type] {
+		return
+	}
+
+	select {
+	case s.ch <- e:
+	default:
+		s.dropped.Add(1)
+	}
+}
@@ -0,0 +1,16 @@
+package filter
+
+const (
+	MuteCategory = "category"
+	MuteUser     = "user"
+	MuteDomain   = "domain"
+)
+
+// Settings lists what a user has hidden or muted. Muted users are listed by
+// username.
+type Settings struct {
+	HiddenPosts     []int    `json:"hidden_posts"`
+	MutedCategories []string `json:"muted_categories"`
+	MutedUsers      []string `json:"muted_users"`
+	MutedDomains    []string `json:"muted_domains"`
+}
@@ -0,0 +1,113 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewFilterHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) HidePost(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Hiding a post")
+	h.handlePost(w, r, h.service.HidePost)
+}
+
+func (h *Handler) UnhidePost(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Unhiding a post")
+	h.handlePost(w, r, h.service.UnhidePost)
+}
+
+func (h *Handler) Mute(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Muting content")
+	h.handleMute(w, r, h.service.Mute)
+}
+
+func (h *Handler) Unmute(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Unmuting content")
+	h.handleMute(w, r, h.service.Unmute)
+}
+
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting content filters")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.service.GetSettings(userID)
+	if err != nil {
+		http.Error(w, "Could not retrieve filters", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(settings); err != nil {
+		http.Error(w, "Failed to encode filters", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request, action func(userID, postID int) error) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["postID"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := action(userID, postID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleMute(w http.ResponseWriter, r *http.Request, action func(userID int, kind, value string) error) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := action(userID, vars["kind"], vars["value"]); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrAlreadyDone), errors.Is(err, ErrNotFiltered):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrUnknownKind), errors.Is(err, ErrMuteSelf), errors.Is(err, ErrInvalidValue):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	}
+}
@@ -0,0 +1,12 @@
+package filter
+
+import "redditclone/internal/post"
+
+type Service interface {
+	HidePost(userID, postID int) error
+	UnhidePost(userID, postID int) error
+	Mute(userID int, kind, value string) error
+	Unmute(userID int, kind, value string) error
+	GetSettings(userID int) (Settings, error)
+	Filter(viewerID int, posts []post.Post) []post.Post
+}
@@ -0,0 +1,228 @@
+package filter
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"redditclone/internal/post"
+	"redditclone/internal/user"
+	"redditclone/internal/utils"
+)
+
+var (
+	ErrUnknownKind  = errors.New("kind must be category, user or domain")
+	ErrMuteSelf     = errors.New("cannot mute yourself")
+	ErrAlreadyDone  = errors.New("already hidden or muted")
+	ErrNotFiltered  = errors.New("not hidden or muted")
+	ErrInvalidValue = errors.New("value must not be empty")
+)
+
+type userFilters struct {
+	hiddenPosts map[int]bool
+	categories  map[string]bool
+	users       map[int]bool
+	domains     map[string]bool
+}
+
+func newUserFilters() *userFilters {
+	return &userFilters{
+		hiddenPosts: make(map[int]bool),
+		categories:  make(map[string]bool),
+		users:       make(map[int]bool),
+		domains:     make(map[string]bool),
+	}
+}
+
+type filterService struct {
+	mu          sync.RWMutex
+	filters     map[int]*userFilters
+	postService post.Service
+	userService user.Service
+}
+
+func NewFilterService(postService post.Service, userService user.Service) Service {
+	return &filterService{
+		filters:     make(map[int]*userFilters),
+		postService: postService,
+		userService: userService,
+	}
+}
+
+func (s *filterService) HidePost(userID, postID int) error {
+	if _, err := s.postService.GetPostByID(postID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f := s.forUser(userID)
+	if f.hiddenPosts[postID] {
+		return ErrAlreadyDone
+	}
+	f.hiddenPosts[postID] = true
+	return nil
+}
+
+func (s *filterService) UnhidePost(userID, postID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f := s.forUser(userID)
+	if !f.hiddenPosts[postID] {
+		return ErrNotFiltered
+	}
+	delete(f.hiddenPosts, postID)
+	return nil
+}
+
+func (s *filterService) Mute(userID int, kind, value string) error {
+	return s.setMute(userID, kind, value, true)
+}
+
+func (s *filterService) Unmute(userID int, kind, value string) error {
+	return s.setMute(userID, kind, value, false)
+}
+
+func (s *filterService) setMute(userID int, kind, value string, muted bool) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ErrInvalidValue
+	}
+
+	var mutedUserID int
+	switch kind {
+	case MuteCategory:
+	case MuteDomain:
+		value = strings.TrimPrefix(strings.ToLower(value), "www.")
+	case MuteUser:
+		u, err := s.userService.GetUserByUsername(value)
+		if err != nil {
+			return err
+		}
+		if u.ID == userID {
+			return ErrMuteSelf
+		}
+		mutedUserID = u.ID
+	default:
+		return ErrUnknownKind
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f := s.forUser(userID)
+	var current bool
+	switch kind {
+	case MuteCategory:
+		current = f.categories[value]
+		setFlag(f.categories, value, muted)
+	case MuteDomain:
+		current = f.domains[value]
+		setFlag(f.domains, value, muted)
+	case MuteUser:
+		current = f.users[mutedUserID]
+		setFlag(f.users, mutedUserID, muted)
+	}
+
+	switch {
+	case muted && current:
+		return ErrAlreadyDone
+	case !muted && !current:
+		return ErrNotFiltered
+	}
+	return nil
+}
+
+func (s *filterService) GetSettings(userID int) (Settings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings := Settings{
+		HiddenPosts:     []int{},
+		MutedCategories: []string{},
+		MutedUsers:      []string{},
+		MutedDomains:    []string{},
+	}
+
+	f, ok := s.filters[userID]
+	if !ok {
+		return settings, nil
+	}
+
+	for id := range f.hiddenPosts {
+		settings.HiddenPosts = append(settings.HiddenPosts, id)
+	}
+	for category := range f.categories {
+		settings.MutedCategories = append(settings.MutedCategories, category)
+	}
+	for id := range f.users {
+		if u, err := s.userService.GetUserByID(id); err == nil {
+			settings.MutedUsers = append(settings.MutedUsers, u.Username)
+		}
+	}
+	for domain := range f.domains {
+		settings.MutedDomains = append(settings.MutedDomains, domain)
+	}
+
+	sort.Ints(settings.HiddenPosts)
+	sort.Strings(settings.MutedCategories)
+	sort.Strings(settings.MutedUsers)
+	sort.Strings(settings.MutedDomains)
+	return settings, nil
+}
+
+// Filter is a post.ListFilter that drops posts the viewer has hidden and
+// posts in muted categories, by muted authors or linking to muted domains.
+func (s *filterService) Filter(viewerID int, posts []post.Post) []post.Post {
+	if viewerID == 0 {
+		return posts
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, ok := s.filters[viewerID]
+	if !ok {
+		return posts
+	}
+
+	visible := make([]post.Post, 0, len(posts))
+	for _, p := range posts {
+		if f.hiddenPosts[p.ID] || f.categories[p.Category] || f.users[p.AuthorID] || f.mutesDomainOf(p) {
+			continue
+		}
+		visible = append(visible, p)
+	}
+	return visible
+}
+
+func (f *userFilters) mutesDomainOf(p post.Post) bool {
+	for domain := range f.domains {
+		if utils.DomainMatches(p.URL, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// forUser returns the user's filters, creating them on first use. The caller
+// holds s.mu for writing.
+func (s *filterService) forUser(userID int) *userFilters {
+	f, ok := s.filters[userID]
+	if !ok {
+		f = newUserFilters()
+		s.filters[userID] = f
+	}
+	return f
+}
+
+func setFlag[K comparable](set map[K]bool, key K, on bool) {
+	if on {
+		set[key] = true
+	} else {
+		delete(set, key)
+	}
+}
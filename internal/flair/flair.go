@@ -0,0 +1,26 @@
+package flair
+
+import "redditclone/internal/post"
+
+// Kinds of templates: post flair is put on posts, user flair next to a
+// user's name in the category.
+const (
+	KindPost = "post"
+	KindUser = "user"
+)
+
+// Template is a flair a category offers. ModOnly templates can only be
+// assigned by the category's moderators.
+type Template struct {
+	ID      int    `json:"id"`
+	Kind    string `json:"kind"`
+	Text    string `json:"text"`
+	Color   string `json:"color,omitempty"`
+	ModOnly bool   `json:"mod_only,omitempty"`
+}
+
+// Flair returns the flair a post or user gets from the template. It is a
+// copy, so changing or deleting the template later leaves it as it is.
+func (t Template) Flair() *post.Flair {
+	return &post.Flair{TemplateID: t.ID, Text: t.Text, Color: t.Color}
+}
@@ -0,0 +1,175 @@
+package flair
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"redditclone/internal/moderation"
+	"redditclone/internal/post"
+	"redditclone/internal/utils"
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewFlairHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// AssignRequest names the template to assign; 0 clears the flair.
+type AssignRequest struct {
+	TemplateID int `json:"template_id"`
+}
+
+func (h *Handler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting flair templates")
+
+	templates, err := h.service.GetTemplates(mux.Vars(r)["category"], r.URL.Query().Get("kind"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(templates); err != nil {
+		http.Error(w, "Failed to encode flair templates", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) AddTemplate(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Adding a flair template")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var template Template
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.AddTemplate(userID, mux.Vars(r)["category"], template)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(template); err != nil {
+		http.Error(w, "Failed to encode flair template", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Deleting a flair template")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	templateID, err := strconv.Atoi(vars["templateID"])
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteTemplate(userID, vars["category"], templateID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) SetPostFlair(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Setting post flair")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["postID"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req AssignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.service.SetPostFlair(userID, postID, req.TemplateID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(p.Version))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		http.Error(w, "Failed to encode post", http.StatusInternalServerError)
+		return
+	}
+}
+
+// SetUserFlair serves both /api/flair/{category}/me and the moderators'
+// /api/mod/{category}/flair/users/{userLogin}.
+func (h *Handler) SetUserFlair(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Setting user flair")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req AssignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := h.service.SetUserFlair(userID, vars["category"], vars["userLogin"], req.TemplateID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, moderation.ErrNotModerator), errors.Is(err, ErrModOnly), errors.Is(err, ErrNotAuthor):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrTemplateNotFound), errors.Is(err, ErrUserNotFound), errors.Is(err, post.ErrPostNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrTooManyTemplates):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,21 @@
+package flair
+
+import "redditclone/internal/post"
+
+type Service interface {
+	GetTemplates(category, kind string) ([]Template, error)
+	AddTemplate(userID int, category string, template Template) (Template, error)
+	DeleteTemplate(userID int, category string, templateID int) error
+
+	// ResolvePostFlair returns the flair userID may put on a new post in
+	// category from the template, as post.Handler expects.
+	ResolvePostFlair(userID int, category string, templateID int) (*post.Flair, error)
+	// SetPostFlair is open to the post's author and the category's
+	// moderators; templateID 0 clears the flair.
+	SetPostFlair(userID, postID, templateID int) (post.Post, error)
+	// SetUserFlair sets username's flair in category, or userID's own when
+	// username is empty. Users set their own from the templates open to
+	// them, moderators anyone's from any.
+	SetUserFlair(userID int, category, username string, templateID int) error
+	ShowUserFlair(viewerID int, posts []post.Post) []post.Post
+}
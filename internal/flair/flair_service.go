@@ -0,0 +1,255 @@
+package flair
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"redditclone/internal/events"
+	"redditclone/internal/moderation"
+	"redditclone/internal/post"
+	"redditclone/internal/user"
+)
+
+const (
+	maxTextLength = 64
+	maxTemplates  = 50
+)
+
+var (
+	ErrTemplateNotFound = errors.New("flair template not found")
+	ErrUnknownKind      = errors.New("kind must be post or user")
+	ErrEmptyText        = errors.New("flair text is required")
+	ErrTextTooLong      = errors.New("flair text must be at most 64 characters")
+	ErrInvalidColor     = errors.New("colour must look like #0079d3")
+	ErrTooManyTemplates = errors.New("a category can have at most 50 flair templates of each kind")
+	ErrModOnly          = errors.New("only moderators can use this flair")
+	ErrNotAuthor        = errors.New("only the author or a moderator can change this post's flair")
+	ErrUserNotFound     = errors.New("user not found")
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type flairService struct {
+	mu        sync.RWMutex
+	templates map[string][]Template
+	users     map[string]map[int]*post.Flair
+	nextID    int
+
+	postService       post.Service
+	userService       user.Service
+	moderationService moderation.Service
+	bus               *events.Bus
+}
+
+// NewFlairService keeps flair templates and user flair per category.
+// Moderators changing flair on someone else's post is published as a
+// ModeratorAction.
+func NewFlairService(postService post.Service, userService user.Service, moderationService moderation.Service, bus *events.Bus) Service {
+	return &flairService{
+		templates:         make(map[string][]Template),
+		users:             make(map[string]map[int]*post.Flair),
+		nextID:            1,
+		postService:       postService,
+		userService:       userService,
+		moderationService: moderationService,
+		bus:               bus,
+	}
+}
+
+// GetTemplates returns the category's templates of kind, or of both kinds
+// when kind is empty, in the order they were added.
+func (s *flairService) GetTemplates(category, kind string) ([]Template, error) {
+	switch kind {
+	case "", KindPost, KindUser:
+	default:
+		return nil, ErrUnknownKind
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	templates := []Template{}
+	for _, t := range s.templates[category] {
+		if kind == "" || t.Kind == kind {
+			templates = append(templates, t)
+		}
+	}
+	return templates, nil
+}
+
+func (s *flairService) AddTemplate(userID int, category string, template Template) (Template, error) {
+	if !s.moderationService.IsModerator(userID, category) {
+		return Template{}, moderation.ErrNotModerator
+	}
+
+	template.Text = strings.TrimSpace(template.Text)
+	switch {
+	case template.Kind != KindPost && template.Kind != KindUser:
+		return Template{}, ErrUnknownKind
+	case template.Text == "":
+		return Template{}, ErrEmptyText
+	case utf8.RuneCountInString(template.Text) > maxTextLength:
+		return Template{}, ErrTextTooLong
+	case template.Color != "" && !colorPattern.MatchString(template.Color):
+		return Template{}, ErrInvalidColor
+	}
+	template.Color = strings.ToLower(template.Color)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, t := range s.templates[category] {
+		if t.Kind == template.Kind {
+			count++
+		}
+	}
+	if count >= maxTemplates {
+		return Template{}, ErrTooManyTemplates
+	}
+
+	template.ID = s.nextID
+	s.nextID++
+	s.templates[category] = append(s.templates[category], template)
+	return template, nil
+}
+
+// DeleteTemplate stops the template from being offered. Flair already
+// assigned from it stays.
+func (s *flairService) DeleteTemplate(userID int, category string, templateID int) error {
+	if !s.moderationService.IsModerator(userID, category) {
+		return moderation.ErrNotModerator
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates := s.templates[category]
+	for i, t := range templates {
+		if t.ID == templateID {
+			s.templates[category] = append(templates[:i:i], templates[i+1:]...)
+			return nil
+		}
+	}
+	return ErrTemplateNotFound
+}
+
+func (s *flairService) ResolvePostFlair(userID int, category string, templateID int) (*post.Flair, error) {
+	t, err := s.usable(userID, category, KindPost, templateID)
+	if err != nil {
+		return nil, err
+	}
+	return t.Flair(), nil
+}
+
+func (s *flairService) SetPostFlair(userID, postID, templateID int) (post.Post, error) {
+	p, err := s.postService.GetPostByID(postID)
+	if err != nil {
+		return post.Post{}, err
+	}
+	moderator := s.moderationService.IsModerator(userID, p.Category)
+	if p.AuthorID != userID && !moderator {
+		return post.Post{}, ErrNotAuthor
+	}
+
+	var flair *post.Flair
+	if templateID != 0 {
+		t, err := s.usable(userID, p.Category, KindPost, templateID)
+		if err != nil {
+			return post.Post{}, err
+		}
+		flair = t.Flair()
+	}
+
+	p, err = s.postService.SetFlair(postID, flair)
+	if err != nil {
+		return post.Post{}, err
+	}
+
+	if p.AuthorID != userID {
+		s.bus.Publish(events.ModeratorAction, moderation.Action{
+			Category:    p.Category,
+			Action:      moderation.ActionFlair,
+			ModeratorID: userID,
+			PostID:      postID,
+			Created:     time.Now(),
+		})
+	}
+	return p, nil
+}
+
+func (s *flairService) SetUserFlair(userID int, category, username string, templateID int) error {
+	targetID := userID
+	if username != "" {
+		target, err := s.userService.GetUserByUsername(username)
+		if err != nil {
+			return ErrUserNotFound
+		}
+		targetID = target.ID
+	}
+	if targetID != userID && !s.moderationService.IsModerator(userID, category) {
+		return moderation.ErrNotModerator
+	}
+
+	var flair *post.Flair
+	if templateID != 0 {
+		t, err := s.usable(userID, category, KindUser, templateID)
+		if err != nil {
+			return err
+		}
+		flair = t.Flair()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if flair == nil {
+		delete(s.users[category], targetID)
+		return nil
+	}
+	if s.users[category] == nil {
+		s.users[category] = make(map[int]*post.Flair)
+	}
+	s.users[category][targetID] = flair
+	return nil
+}
+
+// ShowUserFlair is a post.ListFilter that fills in AuthorFlair with each
+// author's flair in the post's category.
+func (s *flairService) ShowUserFlair(viewerID int, posts []post.Post) []post.Post {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := range posts {
+		posts[i].AuthorFlair = s.users[posts[i].Category][posts[i].AuthorID]
+	}
+	return posts
+}
+
+// usable finds a template of kind in category that userID may assign.
+func (s *flairService) usable(userID int, category, kind string, templateID int) (Template, error) {
+	s.mu.RLock()
+	var (
+		found Template
+		ok    bool
+	)
+	for _, t := range s.templates[category] {
+		if t.ID == templateID && t.Kind == kind {
+			found, ok = t, true
+			break
+		}
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		return Template{}, ErrTemplateNotFound
+	}
+	if found.ModOnly && !s.moderationService.IsModerator(userID, category) {
+		return Template{}, ErrModOnly
+	}
+	return found, nil
+}
@@ -0,0 +1,24 @@
+package karma
+
+import "time"
+
+const (
+	WindowDay  = "day"
+	WindowWeek = "week"
+	WindowAll  = "all"
+)
+
+// windows maps the bounded leaderboard windows to their length; WindowAll is
+// kept separately as a plain running total.
+var windows = map[string]time.Duration{
+	WindowDay:  24 * time.Hour,
+	WindowWeek: 7 * 24 * time.Hour,
+}
+
+// Entry is a single leaderboard row.
+type Entry struct {
+	Rank     int    `json:"rank"`
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Karma    int    `json:"karma"`
+}
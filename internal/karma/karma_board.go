@@ -0,0 +1,112 @@
+package karma
+
+import (
+	"sort"
+	"time"
+)
+
+// bucketSize is the granularity at which karma leaves a bounded window.
+const bucketSize = time.Hour
+
+// scores holds karma per user for each category; the "" category is the
+// site-wide total.
+type scores map[string]map[int]int
+
+func (s scores) add(category string, userID, delta int) {
+	users, ok := s[category]
+	if !ok {
+		users = make(map[int]int)
+		s[category] = users
+	}
+	users[userID] += delta
+	if users[userID] == 0 {
+		delete(users, userID)
+	}
+}
+
+type bucket struct {
+	start  time.Time
+	deltas scores
+}
+
+// board keeps a running total over a sliding window. Every change is also
+// recorded in an hourly bucket, and once a bucket falls out of the window its
+// deltas are subtracted again, so reads never rescan votes.
+type board struct {
+	span    time.Duration
+	totals  scores
+	buckets []*bucket
+}
+
+func newBoard(span time.Duration) *board {
+	return &board{
+		span:   span,
+		totals: make(scores),
+	}
+}
+
+// add records delta at time at. Changes that predate the window, such as the
+// removal of a vote cast long ago, do not affect the board.
+func (b *board) add(at time.Time, category string, userID, delta int) {
+	start := at.Truncate(bucketSize)
+	if !start.After(time.Now().Add(-b.span)) {
+		return
+	}
+
+	var target *bucket
+	for i := len(b.buckets) - 1; i >= 0; i-- {
+		if b.buckets[i].start.Equal(start) {
+			target = b.buckets[i]
+			break
+		}
+		if b.buckets[i].start.Before(start) {
+			break
+		}
+	}
+	if target == nil {
+		target = &bucket{start: start, deltas: make(scores)}
+		b.buckets = append(b.buckets, target)
+		sort.Slice(b.buckets, func(i, j int) bool {
+			return b.buckets[i].start.Before(b.buckets[j].start)
+		})
+	}
+
+	target.deltas.add(category, userID, delta)
+	b.totals.add(category, userID, delta)
+}
+
+// expire drops the buckets that have left the window.
+func (b *board) expire(now time.Time) {
+	cutoff := now.Add(-b.span)
+	n := 0
+	for n < len(b.buckets) && !b.buckets[n].start.After(cutoff) {
+		for category, users := range b.buckets[n].deltas {
+			for userID, delta := range users {
+				b.totals.add(category, userID, -delta)
+			}
+		}
+		n++
+	}
+	b.buckets = b.buckets[n:]
+}
+
+// rank returns the users with positive karma in category, best first.
+func rank(users map[int]int) []Entry {
+	entries := make([]Entry, 0, len(users))
+	for userID, karma := range users {
+		if karma > 0 {
+			entries = append(entries, Entry{UserID: userID, Karma: karma})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Karma != entries[j].Karma {
+			return entries[i].Karma > entries[j].Karma
+		}
+		return entries[i].UserID < entries[j].UserID
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
@@ -0,0 +1,71 @@
+package karma
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"redditclone/internal/user"
+	"redditclone/internal/utils"
+)
+
+type Handler struct {
+	service     Service
+	userService user.Service
+	logger      *log.Logger
+}
+
+func NewKarmaHandler(service Service, userService user.Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service:     service,
+		userService: userService,
+		logger:      logger,
+	}
+}
+
+// GetLeaderboard serves both the site-wide board and, when the route has a
+// category, that category's top contributors. The window defaults to all
+// time.
+func (h *Handler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting leaderboard")
+
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = WindowAll
+	}
+
+	page, err := utils.ParsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.service.Leaderboard(mux.Vars(r)["category"], window)
+	if err != nil {
+		if errors.Is(err, ErrUnknownWindow) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Could not retrieve leaderboard", http.StatusInternalServerError)
+		return
+	}
+
+	total := len(entries)
+	start, end := page.Bounds(total)
+	entries = entries[start:end]
+	for i := range entries {
+		if u, err := h.userService.GetUserByID(entries[i].UserID); err == nil {
+			entries[i].Username = u.Username
+		}
+	}
+
+	utils.SetTotal(w, total)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		http.Error(w, "Failed to encode leaderboard", http.StatusInternalServerError)
+		return
+	}
+}
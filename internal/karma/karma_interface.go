@@ -0,0 +1,8 @@
+package karma
+
+type Service interface {
+	Karma(userID int) (postKarma, commentKarma int)
+	// Leaderboard returns the users with positive karma earned within window,
+	// best first. An empty category means the site-wide board.
+	Leaderboard(category, window string) ([]Entry, error)
+}
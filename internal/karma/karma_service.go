@@ -0,0 +1,186 @@
+package karma
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"redditclone/internal/comment"
+	"redditclone/internal/events"
+	"redditclone/internal/post"
+)
+
+var ErrUnknownWindow = errors.New("window must be one of day, week or all")
+
+type totals struct {
+	post    int
+	comment int
+}
+
+// content identifies a voted item; comment IDs are unique across posts.
+type content struct {
+	comment bool
+	id      int
+}
+
+type karmaService struct {
+	mu         sync.RWMutex
+	totals     map[int]*totals
+	allTime    scores
+	boards     map[string]*board
+	categories map[int]string
+	votedAt    map[content]map[int]time.Time
+}
+
+// NewKarmaService keeps running karma totals per author, updated from vote
+// events: every vote moves the author's karma by the difference between the
+// voter's new and previous vote. The same deltas feed the leaderboards.
+func NewKarmaService(bus *events.Bus) Service {
+	s := &karmaService{
+		totals:     make(map[int]*totals),
+		allTime:    make(scores),
+		boards:     make(map[string]*board, len(windows)),
+		categories: make(map[int]string),
+		votedAt:    make(map[content]map[int]time.Time),
+	}
+	for name, span := range windows {
+		s.boards[name] = newBoard(span)
+	}
+
+	bus.Subscribe(events.PostCreated, s.postCreated)
+	bus.Subscribe(events.PostPurged, s.postPurged)
+	bus.Subscribe(events.CommentPurged, s.commentPurged)
+	bus.Subscribe(events.PostVoted, s.postVoted)
+	bus.Subscribe(events.CommentVoted, s.commentVoted)
+
+	return s
+}
+
+func (s *karmaService) Karma(userID int) (int, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.totals[userID]
+	if !ok {
+		return 0, 0
+	}
+	return t.post, t.comment
+}
+
+func (s *karmaService) Leaderboard(category, window string) ([]Entry, error) {
+	if window == WindowAll {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return rank(s.allTime[category]), nil
+	}
+
+	if _, ok := s.boards[window]; !ok {
+		return nil, ErrUnknownWindow
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.boards[window]
+	b.expire(time.Now())
+	return rank(b.totals[category]), nil
+}
+
+func (s *karmaService) postCreated(e events.Event) {
+	if p, ok := e.Payload.(post.Post); ok {
+		s.mu.Lock()
+		s.categories[p.ID] = p.Category
+		s.mu.Unlock()
+	}
+}
+
+func (s *karmaService) postPurged(e events.Event) {
+	if p, ok := e.Payload.(post.Post); ok {
+		s.mu.Lock()
+		delete(s.categories, p.ID)
+		delete(s.votedAt, content{id: p.ID})
+		s.mu.Unlock()
+	}
+}
+
+func (s *karmaService) commentPurged(e events.Event) {
+	if c, ok := e.Payload.(comment.Comment); ok {
+		s.mu.Lock()
+		delete(s.votedAt, content{comment: true, id: c.ID})
+		s.mu.Unlock()
+	}
+}
+
+func (s *karmaService) postVoted(e events.Event) {
+	vote, ok := e.Payload.(post.VoteEvent)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.totalsFor(vote.Post.AuthorID).post += vote.Vote - vote.Previous
+	s.record(content{id: vote.Post.ID}, vote.Post.Category, vote.Post.AuthorID, vote.UserID, vote.Previous, vote.Vote)
+}
+
+func (s *karmaService) commentVoted(e events.Event) {
+	vote, ok := e.Payload.(comment.VoteEvent)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.totalsFor(vote.Comment.AuthorID).comment += vote.Vote - vote.Previous
+	category := s.categories[vote.Comment.PostID]
+	s.record(content{comment: true, id: vote.Comment.ID}, category, vote.Comment.AuthorID, vote.UserID, vote.Previous, vote.Vote)
+}
+
+// record moves the leaderboards for a changed vote. The previous vote is
+// taken back from the window it was cast in, so a vote removed today does
+// not cost karma on today's board for a vote cast last month.
+func (s *karmaService) record(item content, category string, authorID, voterID, previous, vote int) {
+	now := time.Now()
+	voters, ok := s.votedAt[item]
+	if !ok {
+		voters = make(map[int]time.Time)
+		s.votedAt[item] = voters
+	}
+
+	if previous != 0 {
+		castAt := voters[voterID]
+		s.apply(castAt, category, authorID, -previous)
+	}
+	if vote != 0 {
+		voters[voterID] = now
+		s.apply(now, category, authorID, vote)
+	} else {
+		delete(voters, voterID)
+	}
+}
+
+func (s *karmaService) apply(at time.Time, category string, authorID, delta int) {
+	s.allTime.add("", authorID, delta)
+	if category != "" {
+		s.allTime.add(category, authorID, delta)
+	}
+
+	for _, b := range s.boards {
+		b.expire(time.Now())
+		b.add(at, "", authorID, delta)
+		if category != "" {
+			b.add(at, category, authorID, delta)
+		}
+	}
+}
+
+func (s *karmaService) totalsFor(userID int) *totals {
+	t, ok := s.totals[userID]
+	if !ok {
+		t = &totals{}
+		s.totals[userID] = t
+	}
+	return t
+}
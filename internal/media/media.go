@@ -0,0 +1,24 @@
+package media
+
+// Limits on uploaded images. MaxPixels guards against small files that
+// decode into huge images.
+const (
+	MaxSize      = 10 << 20
+	MaxDimension = 8000
+	MaxPixels    = 25_000_000
+)
+
+// size is a thumbnail size: the image is scaled down to fit in a square of
+// max pixels.
+type size struct {
+	name string
+	max  int
+}
+
+// sizes are the thumbnails made for every image, smallest first. Sizes not
+// smaller than the image itself are skipped.
+var sizes = []size{
+	{name: "small", max: 140},
+	{name: "medium", max: 320},
+	{name: "large", max: 640},
+}
@@ -0,0 +1,147 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// tiffEntry is one IFD entry of type SHORT.
+type tiffEntry struct {
+	tag, value uint16
+}
+
+// tiffData builds the TIFF structure EXIF keeps its tags in, with a single
+// IFD holding entries.
+func tiffData(order binary.ByteOrder, entries ...tiffEntry) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(8))
+	binary.Write(&buf, order, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&buf, order, e.tag)
+		binary.Write(&buf, order, uint16(3))
+		binary.Write(&buf, order, uint32(1))
+		binary.Write(&buf, order, e.value)
+		binary.Write(&buf, order, uint16(0))
+	}
+	binary.Write(&buf, order, uint32(0))
+	return buf.Bytes()
+}
+
+// segment builds a JPEG marker segment.
+func segment(marker byte, payload []byte) []byte {
+	s := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(s[2:], uint16(len(payload)+2))
+	return append(s, payload...)
+}
+
+func exifSegment(tiff []byte) []byte {
+	return segment(0xE1, append([]byte("Exif\x00\x00"), tiff...))
+}
+
+// withSegments inserts segments right after the SOI marker of a JPEG.
+func withSegments(jpg []byte, segments ...[]byte) []byte {
+	data := append([]byte(nil), jpg[:2]...)
+	for _, s := range segments {
+		data = append(data, s...)
+	}
+	return append(data, jpg[2:]...)
+}
+
+func testJPEG(t testing.TB, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestEXIFOrientation(t *testing.T) {
+	jpg := testJPEG(t, 8, 8)
+	exif := func(order binary.ByteOrder, entries ...tiffEntry) []byte {
+		return withSegments(jpg, exifSegment(tiffData(order, entries...)))
+	}
+	jfif := segment(0xE0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", jpg, 1},
+		{"little endian", exif(binary.LittleEndian, tiffEntry{orientationTag, 6}), 6},
+		{"big endian", exif(binary.BigEndian, tiffEntry{orientationTag, 8}), 8},
+		{"after other tags", exif(binary.BigEndian, tiffEntry{0x010F, 1}, tiffEntry{orientationTag, 3}), 3},
+		{"after jfif segment", withSegments(jpg, jfif, exifSegment(tiffData(binary.LittleEndian, tiffEntry{orientationTag, 5}))), 5},
+		{"no orientation tag", exif(binary.LittleEndian, tiffEntry{0x010F, 6}), 1},
+		{"orientation out of range", exif(binary.LittleEndian, tiffEntry{orientationTag, 9}), 1},
+		{"zero orientation", exif(binary.LittleEndian, tiffEntry{orientationTag, 0}), 1},
+		{"unknown byte order", withSegments(jpg, exifSegment(append([]byte("XX"), tiffData(binary.LittleEndian, tiffEntry{orientationTag, 6})[2:]...))), 1},
+		{"ifd past the end", withSegments(jpg, exifSegment([]byte("II\x2A\x00\xFF\x00\x00\x00"))), 1},
+		{"truncated entry", withSegments(jpg, exifSegment(tiffData(binary.LittleEndian, tiffEntry{orientationTag, 6})[:14])), 1},
+		{"segment longer than file", exif(binary.LittleEndian, tiffEntry{orientationTag, 6})[:20], 1},
+		{"app1 without exif header", withSegments(jpg, segment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00"))), 1},
+		{"not a jpeg", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exifOrientation(tt.data); got != tt.want {
+				t.Errorf("exifOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	const w, h = 3, 2
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	marked := color.RGBA{R: 255, A: 255}
+	src.Set(0, 0, marked)
+
+	// Each orientation is told apart by the size of the result and where the
+	// stored image's top left pixel ends up.
+	tests := []struct {
+		orientation int
+		w, h        int
+		x, y        int
+	}{
+		{1, w, h, 0, 0},
+		{2, w, h, w - 1, 0},
+		{3, w, h, w - 1, h - 1},
+		{4, w, h, 0, h - 1},
+		{5, h, w, 0, 0},
+		{6, h, w, h - 1, 0},
+		{7, h, w, h - 1, w - 1},
+		{8, h, w, 0, w - 1},
+		{0, w, h, 0, 0},
+		{9, w, h, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(rune('0'+tt.orientation)), func(t *testing.T) {
+			got := orient(src, tt.orientation)
+			b := got.Bounds()
+			if b.Dx() != tt.w || b.Dy() != tt.h {
+				t.Fatalf("got %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.w, tt.h)
+			}
+			for y := 0; y < tt.h; y++ {
+				for x := 0; x < tt.w; x++ {
+					isMarked := color.RGBAModel.Convert(got.At(b.Min.X+x, b.Min.Y+y)) == marked
+					if isMarked != (x == tt.x && y == tt.y) {
+						t.Errorf("pixel (%d, %d) marked = %v, want the mark at (%d, %d)", x, y, isMarked, tt.x, tt.y)
+					}
+				}
+			}
+		})
+	}
+}
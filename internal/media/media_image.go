@@ -0,0 +1,155 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+var (
+	ErrTooLarge        = errors.New("image must be at most 10 MB")
+	ErrUnsupportedType = errors.New("image must be a JPEG, PNG or GIF")
+	ErrInvalidImage    = errors.New("image could not be read")
+	ErrDimensions      = errors.New("image must be at most 8000 pixels on each side and 25 megapixels in all")
+)
+
+const jpegQuality = 90
+
+// extensions maps the content types accepted to the extension files are
+// stored with.
+var extensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// encoded is an image ready to be stored.
+type encoded struct {
+	data          []byte
+	contentType   string
+	width, height int
+}
+
+// decode checks an upload and decodes it. The content type comes from the
+// bytes themselves, never from the client, and the dimensions are checked
+// before the pixels are decoded. Only the first frame of a GIF is kept: a
+// small file can hold any number of large frames.
+func decode(data []byte) (image.Image, string, error) {
+	if len(data) > MaxSize {
+		return nil, "", ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := extensions[contentType]; !ok {
+		return nil, "", ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrInvalidImage
+	}
+	if config.Width < 1 || config.Height < 1 ||
+		config.Width > MaxDimension || config.Height > MaxDimension ||
+		config.Width*config.Height > MaxPixels {
+		return nil, "", ErrDimensions
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrInvalidImage
+	}
+	if contentType == "image/jpeg" {
+		img = orient(img, exifOrientation(data))
+	}
+	return img, contentType, nil
+}
+
+// encode writes img afresh in the format of contentType. Only pixels are
+// written, so EXIF data, comments and any other metadata of the upload are
+// left behind.
+func encode(img image.Image, contentType string) (encoded, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	case "image/gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return encoded{}, err
+	}
+
+	b := img.Bounds()
+	return encoded{data: buf.Bytes(), contentType: contentType, width: b.Dx(), height: b.Dy()}, nil
+}
+
+// thumbnailType is the format thumbnails of contentType are stored in:
+// scaling blends colours a GIF palette cannot hold.
+func thumbnailType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return contentType
+	}
+	return "image/png"
+}
+
+// fit returns the size of a w×h image scaled down to fit in a bound×bound
+// square, keeping its aspect ratio.
+func fit(w, h, bound int) (int, int) {
+	if w >= h {
+		return bound, max(1, h*bound/w)
+	}
+	return max(1, w*bound/h), bound
+}
+
+// scale shrinks src to w×h by averaging the source pixels behind each
+// destination pixel, which keeps downscaled images smooth.
+func scale(src *image.RGBA, w, h int) *image.RGBA {
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.PixOffset(sb.Min.X+x0, sb.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(src.Pix[row])
+					g += uint64(src.Pix[row+1])
+					b += uint64(src.Pix[row+2])
+					a += uint64(src.Pix[row+3])
+					row += 4
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// toRGBA converts img so that scale can read its pixels directly.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,172 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+// pngHeader returns a PNG whose header claims w×h pixels, so that the size
+// checks can be tested without encoding huge images.
+func pngHeader(t testing.TB, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// The IHDR chunk follows the 8 byte signature: length, type, data, CRC.
+	ihdr := data[8+4 : 8+4+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(w))
+	binary.BigEndian.PutUint32(ihdr[8:], uint32(h))
+	binary.BigEndian.PutUint32(data[8+4+4+13:], crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+func testPNG(t testing.TB, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testGIF(t testing.TB, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	rotated := withSegments(testJPEG(t, 40, 20), exifSegment(tiffData(binary.BigEndian, tiffEntry{orientationTag, 6})))
+
+	tests := []struct {
+		name        string
+		data        []byte
+		err         error
+		contentType string
+		w, h        int
+	}{
+		{name: "jpeg", data: testJPEG(t, 40, 20), contentType: "image/jpeg", w: 40, h: 20},
+		{name: "jpeg turned upright", data: rotated, contentType: "image/jpeg", w: 20, h: 40},
+		{name: "png", data: testPNG(t, 30, 10), contentType: "image/png", w: 30, h: 10},
+		{name: "gif", data: testGIF(t, 5, 7), contentType: "image/gif", w: 5, h: 7},
+		{name: "largest side passes the size check", data: pngHeader(t, MaxDimension, 1), err: ErrInvalidImage},
+		{name: "too large", data: make([]byte, MaxSize+1), err: ErrTooLarge},
+		{name: "text", data: []byte("hello, world"), err: ErrUnsupportedType},
+		{name: "svg", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), err: ErrUnsupportedType},
+		{name: "empty", data: nil, err: ErrUnsupportedType},
+		{name: "broken jpeg", data: []byte("\xFF\xD8\xFF\xE0 not really a jpeg"), err: ErrInvalidImage},
+		{name: "too wide", data: pngHeader(t, MaxDimension+1, 1), err: ErrDimensions},
+		{name: "too tall", data: pngHeader(t, 1, MaxDimension+1), err: ErrDimensions},
+		{name: "too many pixels", data: pngHeader(t, 5001, 5000), err: ErrDimensions},
+		{name: "zero width", data: pngHeader(t, 0, 10), err: ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, contentType, err := decode(tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("decode() error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if contentType != tt.contentType {
+				t.Errorf("content type = %q, want %q", contentType, tt.contentType)
+			}
+			if b := img.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+				t.Errorf("got %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.w, tt.h)
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, bound int
+		wantW       int
+		wantH       int
+	}{
+		{640, 480, 140, 140, 105},
+		{480, 640, 140, 105, 140},
+		{300, 300, 140, 140, 140},
+		{8000, 1, 320, 320, 1},
+		{1, 8000, 320, 1, 320},
+		{1000, 999, 640, 640, 639},
+	}
+	for _, tt := range tests {
+		w, h := fit(tt.w, tt.h, tt.bound)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("fit(%d, %d, %d) = %dx%d, want %dx%d", tt.w, tt.h, tt.bound, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	black := color.RGBA{A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+	halves := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	checks := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			if x < 2 {
+				halves.Set(x, y, red)
+			} else {
+				halves.Set(x, y, blue)
+			}
+		}
+		for x := 0; x < 2; x++ {
+			if (x+y)%2 == 0 {
+				checks.Set(x, y, black)
+			} else {
+				checks.Set(x, y, white)
+			}
+		}
+	}
+	// A sub-image does not start at the origin.
+	offset := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	offset.Set(1, 1, blue)
+	offset.Set(2, 1, blue)
+	offset.Set(1, 2, blue)
+	offset.Set(2, 2, blue)
+
+	tests := []struct {
+		name string
+		src  *image.RGBA
+		w, h int
+		want []color.RGBA
+	}{
+		{"halves keep their colours", halves, 2, 1, []color.RGBA{red, blue}},
+		{"checks average to grey", checks, 1, 1, []color.RGBA{{R: 127, G: 127, B: 127, A: 255}}},
+		{"same size", checks, 2, 2, []color.RGBA{black, white, white, black}},
+		{"sub-image", offset.SubImage(image.Rect(1, 1, 3, 3)).(*image.RGBA), 1, 1, []color.RGBA{blue}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scale(tt.src, tt.w, tt.h)
+			if b := got.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+				t.Fatalf("got %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.w, tt.h)
+			}
+			for i, want := range tt.want {
+				x, y := i%tt.w, i/tt.w
+				if c := got.RGBAAt(x, y); c != want {
+					t.Errorf("pixel (%d, %d) = %v, want %v", x, y, c, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package media
+
+import "redditclone/internal/post"
+
+type Service interface {
+	// Upload validates an uploaded image, strips its metadata, makes its
+	// thumbnails and stores them all.
+	Upload(data []byte) (post.Image, error)
+	// Delete removes an image and its thumbnails from storage.
+	Delete(img post.Image)
+}
@@ -0,0 +1,122 @@
+package media
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"path"
+
+	"redditclone/internal/events"
+	"redditclone/internal/post"
+)
+
+type mediaService struct {
+	storage Storage
+	logger  *log.Logger
+}
+
+// NewMediaService stores images in storage and deletes them once their post
+// has been purged.
+func NewMediaService(storage Storage, bus *events.Bus, logger *log.Logger) Service {
+	s := &mediaService{
+		storage: storage,
+		logger:  logger,
+	}
+
+	bus.Subscribe(events.PostPurged, s.postPurged)
+
+	return s
+}
+
+// Upload encodes the image and every thumbnail before storing anything, and
+// stores either all of the files or none.
+func (s *mediaService) Upload(data []byte) (post.Image, error) {
+	img, contentType, err := decode(data)
+	if err != nil {
+		return post.Image{}, err
+	}
+
+	original, err := encode(img, contentType)
+	if err != nil {
+		return post.Image{}, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return post.Image{}, err
+	}
+
+	name := id + "." + extensions[contentType]
+	files := map[string][]byte{name: original.data}
+	result := post.Image{
+		URL:         s.storage.URL(name),
+		ContentType: contentType,
+		Width:       original.width,
+		Height:      original.height,
+		Size:        int64(len(original.data)),
+		Thumbnails:  []post.Thumbnail{},
+	}
+
+	rgba := toRGBA(img)
+	thumbType := thumbnailType(contentType)
+	for _, size := range sizes {
+		if original.width <= size.max && original.height <= size.max {
+			break
+		}
+
+		w, h := fit(original.width, original.height, size.max)
+		thumb, err := encode(scale(rgba, w, h), thumbType)
+		if err != nil {
+			return post.Image{}, err
+		}
+
+		thumbName := id + "_" + size.name + "." + extensions[thumbType]
+		files[thumbName] = thumb.data
+		result.Thumbnails = append(result.Thumbnails, post.Thumbnail{
+			Name:   size.name,
+			URL:    s.storage.URL(thumbName),
+			Width:  w,
+			Height: h,
+		})
+	}
+
+	var saved []string
+	for name, data := range files {
+		if err := s.storage.Save(name, data); err != nil {
+			for _, name := range saved {
+				s.storage.Delete(name)
+			}
+			return post.Image{}, err
+		}
+		saved = append(saved, name)
+	}
+
+	return result, nil
+}
+
+func (s *mediaService) Delete(img post.Image) {
+	names := []string{path.Base(img.URL)}
+	for _, thumb := range img.Thumbnails {
+		names = append(names, path.Base(thumb.URL))
+	}
+
+	for _, name := range names {
+		if err := s.storage.Delete(name); err != nil {
+			s.logger.Printf("Could not delete %s: %v\n", name, err)
+		}
+	}
+}
+
+func (s *mediaService) postPurged(e events.Event) {
+	if p, ok := e.Payload.(post.Post); ok && p.Image != nil {
+		s.Delete(*p.Image)
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,128 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/jpeg"
+	"io"
+	"log"
+	"path"
+	"testing"
+
+	"redditclone/internal/events"
+)
+
+// memoryStorage keeps files in a map and fails the save after failAfter
+// successful ones, when it is set.
+type memoryStorage struct {
+	files     map[string][]byte
+	saves     int
+	failAfter int
+}
+
+var errStorageFull = errors.New("storage full")
+
+func (s *memoryStorage) Save(name string, data []byte) error {
+	if s.failAfter > 0 && s.saves == s.failAfter {
+		return errStorageFull
+	}
+	s.saves++
+	s.files[name] = data
+	return nil
+}
+
+func (s *memoryStorage) Delete(name string) error {
+	delete(s.files, name)
+	return nil
+}
+
+func (s *memoryStorage) URL(name string) string {
+	return "/media/" + name
+}
+
+// jpegMarkers lists the markers of the segments before the image data.
+func jpegMarkers(data []byte) []byte {
+	var markers []byte
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xFF; {
+		marker := data[pos+1]
+		if marker == 0xDA {
+			break
+		}
+		markers = append(markers, marker)
+		pos += 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+	}
+	return markers
+}
+
+func newTestMediaService(storage Storage) Service {
+	return NewMediaService(storage, events.NewBus(), log.New(io.Discard, "", 0))
+}
+
+func TestUploadStripsEXIF(t *testing.T) {
+	tiff := tiffData(binary.LittleEndian, tiffEntry{orientationTag, 6}, tiffEntry{0x010F, 1})
+	upload := withSegments(testJPEG(t, 800, 400), exifSegment(tiff))
+	if bytes.IndexByte(jpegMarkers(upload), 0xE1) < 0 {
+		t.Fatal("test upload has no APP1 segment")
+	}
+
+	storage := &memoryStorage{files: make(map[string][]byte)}
+	img, err := newTestMediaService(storage).Upload(upload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Orientation 6 is applied to the pixels, so the stored image is upright.
+	if img.Width != 400 || img.Height != 800 {
+		t.Errorf("stored %dx%d, want 400x800", img.Width, img.Height)
+	}
+	if len(img.Thumbnails) != len(sizes) {
+		t.Errorf("got %d thumbnails, want %d", len(img.Thumbnails), len(sizes))
+	}
+	if len(storage.files) != len(sizes)+1 {
+		t.Errorf("stored %d files, want %d", len(storage.files), len(sizes)+1)
+	}
+
+	for name, data := range storage.files {
+		if bytes.IndexByte(jpegMarkers(data), 0xE1) >= 0 {
+			t.Errorf("%s keeps an APP1 segment", name)
+		}
+		if bytes.Contains(data, []byte("Exif\x00\x00")) {
+			t.Errorf("%s keeps EXIF data", name)
+		}
+		config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if config.Width > config.Height {
+			t.Errorf("%s is %dx%d, want it upright", name, config.Width, config.Height)
+		}
+	}
+}
+
+func TestUploadStoresAllOrNothing(t *testing.T) {
+	storage := &memoryStorage{files: make(map[string][]byte), failAfter: 2}
+	_, err := newTestMediaService(storage).Upload(testPNG(t, 700, 700))
+	if !errors.Is(err, errStorageFull) {
+		t.Fatalf("Upload() error = %v, want %v", err, errStorageFull)
+	}
+	if len(storage.files) != 0 {
+		t.Errorf("%d files left behind after a failed upload", len(storage.files))
+	}
+}
+
+func TestDelete(t *testing.T) {
+	storage := &memoryStorage{files: make(map[string][]byte)}
+	service := newTestMediaService(storage)
+	img, err := service.Upload(testPNG(t, 400, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.files["other.png"] = nil
+
+	service.Delete(img)
+	if _, ok := storage.files[path.Base(img.URL)]; ok || len(storage.files) != 1 {
+		t.Errorf("files left after Delete: %d", len(storage.files))
+	}
+}
@@ -0,0 +1,117 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage keeps uploaded files under flat names such as "3f2a….jpg".
+type Storage interface {
+	Save(name string, data []byte) error
+	Delete(name string) error
+	// URL is the address the file is served from.
+	URL(name string) string
+}
+
+type localStorage struct {
+	dir       string
+	urlPrefix string
+}
+
+// NewLocalStorage stores files in dir, creating it if needed. The files are
+// expected to be served under urlPrefix.
+func NewLocalStorage(dir, urlPrefix string) (Storage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating media directory: %w", err)
+	}
+	return &localStorage{
+		dir:       dir,
+		urlPrefix: strings.TrimSuffix(urlPrefix, "/") + "/",
+	}, nil
+}
+
+// Save writes to a temporary file first, so a file is never served half
+// written.
+func (s *localStorage) Save(name string, data []byte) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *localStorage) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *localStorage) URL(name string) string {
+	return s.urlPrefix + name
+}
+
+// path refuses names that would leave the storage directory.
+func (s *localStorage) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+type fileSystem struct {
+	http.FileSystem
+}
+
+// NewFileSystem serves the files of a local storage directory. Directories
+// and hidden files, such as uploads still being written, are reported as
+// missing, so http.FileServer neither lists the directory nor serves them.
+func NewFileSystem(dir string) http.FileSystem {
+	return fileSystem{http.Dir(dir)}
+}
+
+func (fs fileSystem) Open(name string) (http.File, error) {
+	if strings.HasPrefix(filepath.Base(name), ".") {
+		return nil, os.ErrNotExist
+	}
+
+	f, err := fs.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+	return f, nil
+}
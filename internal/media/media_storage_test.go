@@ -0,0 +1,90 @@
+package media
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStorageNames(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocalStorage(filepath.Join(dir, "media"), "/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "outside.jpg"), []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"3f2a.jpg", true},
+		{"3f2a_small.png", true},
+		{"", false},
+		{".upload-123", false},
+		{".", false},
+		{"..", false},
+		{"../outside.jpg", false},
+		{"sub/3f2a.jpg", false},
+		{"/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saveErr := s.Save(tt.name, []byte("data"))
+			deleteErr := s.Delete(tt.name)
+			if tt.valid && (saveErr != nil || deleteErr != nil) {
+				t.Errorf("Save() = %v, Delete() = %v, want no errors", saveErr, deleteErr)
+			}
+			if !tt.valid && (saveErr == nil || deleteErr == nil) {
+				t.Errorf("Save() = %v, Delete() = %v, want both to refuse the name", saveErr, deleteErr)
+			}
+		})
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "outside.jpg")); err != nil || string(data) != "keep" {
+		t.Errorf("file outside the storage directory changed: %q, %v", data, err)
+	}
+	if got := s.URL("3f2a.jpg"); got != "/media/3f2a.jpg" {
+		t.Errorf("URL() = %q", got)
+	}
+	if err := s.Delete("missing.jpg"); err != nil {
+		t.Errorf("deleting a missing file: %v", err)
+	}
+}
+
+func TestFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{"3f2a.jpg": "image", ".upload-1": "partial"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewFileSystem(dir)
+	tests := []struct {
+		path string
+		err  error
+	}{
+		{"/3f2a.jpg", nil},
+		{"/", os.ErrNotExist},
+		{"/sub", os.ErrNotExist},
+		{"/sub/", os.ErrNotExist},
+		{"/.upload-1", os.ErrNotExist},
+		{"/missing.jpg", os.ErrNotExist},
+	}
+	for _, tt := range tests {
+		f, err := fs.Open(tt.path)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Open(%q) error = %v, want %v", tt.path, err, tt.err)
+		}
+		if f != nil {
+			f.Close()
+		}
+	}
+}
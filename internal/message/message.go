@@ -0,0 +1,28 @@
+package message
+
+import "time"
+
+// Message is a private message from SenderID to RecipientID.
+type Message struct {
+	ID             int       `json:"id"`
+	ConversationID int       `json:"conversation_id"`
+	SenderID       int       `json:"sender_id"`
+	RecipientID    int       `json:"recipient_id"`
+	Text           string    `json:"text"`
+	Read           bool      `json:"read"`
+	Created        time.Time `json:"created"`
+}
+
+// Conversation is the thread of messages between two users. Unread counts
+// the messages the viewing user has not read yet.
+type Conversation struct {
+	ID          int       `json:"id"`
+	UserIDs     [2]int    `json:"user_ids"`
+	LastMessage *Message  `json:"last_message,omitempty"`
+	Unread      int       `json:"unread"`
+	Updated     time.Time `json:"updated"`
+}
+
+func (c Conversation) Has(userID int) bool {
+	return c.UserIDs[0] == userID || c.UserIDs[1] == userID
+}
@@ -0,0 +1,183 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"redditclone/internal/utils"
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewMessageHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+type SendMessageRequest struct {
+	Text string `json:"text"`
+}
+
+func (h *Handler) Send(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Sending a message")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req SendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	m, err := h.service.Send(userID, mux.Vars(r)["userLogin"], req.Text)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecipientNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrBlocked), errors.Is(err, ErrRecipientBlocked):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, ErrMessageSelf), errors.Is(err, ErrEmptyMessage), errors.Is(err, ErrMessageTooLong):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Could not send message", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		http.Error(w, "Failed to encode message", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) GetConversations(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting conversations")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, err := utils.ParsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conversations, err := h.service.GetConversations(userID)
+	if err != nil {
+		http.Error(w, "Could not retrieve conversations", http.StatusInternalServerError)
+		return
+	}
+
+	start, end := page.Bounds(len(conversations))
+	utils.SetTotal(w, len(conversations))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(conversations[start:end]); err != nil {
+		http.Error(w, "Failed to encode conversations", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting messages")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conversationID, err := strconv.Atoi(mux.Vars(r)["conversationID"])
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := utils.ParsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	messages, err := h.service.GetMessages(userID, conversationID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	start, end := page.Bounds(len(messages))
+	utils.SetTotal(w, len(messages))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(messages[start:end]); err != nil {
+		http.Error(w, "Failed to encode messages", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Marking conversation as read")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conversationID, err := strconv.Atoi(mux.Vars(r)["conversationID"])
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	marked, err := h.service.MarkRead(userID, conversationID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]int{"marked": marked}); err != nil {
+		http.Error(w, "Failed to encode result", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting unread message count")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	unread, err := h.service.UnreadCount(userID)
+	if err != nil {
+		http.Error(w, "Could not count unread messages", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]int{"unread": unread}); err != nil {
+		http.Error(w, "Failed to encode unread count", http.StatusInternalServerError)
+		return
+	}
+}
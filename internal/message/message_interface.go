@@ -0,0 +1,21 @@
+package message
+
+// Repository stores conversations and messages. Conversations and
+// unread counts are returned as seen by the given user.
+type Repository interface {
+	GetOrCreateConversation(userID, otherID int) (Conversation, error)
+	GetConversation(conversationID, userID int) (Conversation, error)
+	GetConversations(userID int) ([]Conversation, error)
+	AddMessage(m Message) (Message, error)
+	GetMessages(conversationID int) ([]Message, error)
+	MarkRead(conversationID, userID int) (int, error)
+	UnreadCount(userID int) (int, error)
+}
+
+type Service interface {
+	Send(senderID int, recipient string, text string) (Message, error)
+	GetConversations(userID int) ([]Conversation, error)
+	GetMessages(userID, conversationID int) ([]Message, error)
+	MarkRead(userID, conversationID int) (int, error)
+	UnreadCount(userID int) (int, error)
+}
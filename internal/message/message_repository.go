@@ -0,0 +1,181 @@
+package message
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrConversationNotFound = errors.New("conversation not found")
+
+type pair struct {
+	low, high int
+}
+
+func pairOf(a, b int) pair {
+	if a > b {
+		a, b = b, a
+	}
+	return pair{low: a, high: b}
+}
+
+type memoryRepository struct {
+	mu             sync.RWMutex
+	conversations  map[int]*Conversation
+	byPair         map[pair]int
+	messages       map[int][]Message
+	conversationID int
+	messageID      int
+}
+
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		conversations:  make(map[int]*Conversation),
+		byPair:         make(map[pair]int),
+		messages:       make(map[int][]Message),
+		conversationID: 1,
+		messageID:      1,
+	}
+}
+
+func (r *memoryRepository) GetOrCreateConversation(userID, otherID int) (Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := pairOf(userID, otherID)
+	if id, ok := r.byPair[key]; ok {
+		return r.view(r.conversations[id], userID), nil
+	}
+
+	c := &Conversation{
+		ID:      r.conversationID,
+		UserIDs: [2]int{key.low, key.high},
+		Updated: time.Now(),
+	}
+	r.conversationID++
+	r.conversations[c.ID] = c
+	r.byPair[key] = c.ID
+
+	return r.view(c, userID), nil
+}
+
+func (r *memoryRepository) GetConversation(conversationID, userID int) (Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.conversations[conversationID]
+	if !ok || !c.Has(userID) {
+		return Conversation{}, ErrConversationNotFound
+	}
+	return r.view(c, userID), nil
+}
+
+// GetConversations returns the user's conversations with at least one
+// message, most recently active first.
+func (r *memoryRepository) GetConversations(userID int) ([]Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conversations := []Conversation{}
+	for _, c := range r.conversations {
+		if c.Has(userID) && c.LastMessage != nil {
+			conversations = append(conversations, r.view(c, userID))
+		}
+	}
+
+	sort.Slice(conversations, func(i, j int) bool {
+		return conversations[i].Updated.After(conversations[j].Updated)
+	})
+	return conversations, nil
+}
+
+func (r *memoryRepository) AddMessage(m Message) (Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.conversations[m.ConversationID]
+	if !ok {
+		return Message{}, ErrConversationNotFound
+	}
+
+	m.ID = r.messageID
+	m.Created = time.Now()
+	r.messageID++
+	r.messages[c.ID] = append(r.messages[c.ID], m)
+
+	last := m
+	c.LastMessage = &last
+	c.Updated = m.Created
+	return m, nil
+}
+
+// GetMessages returns the conversation's messages, newest first.
+func (r *memoryRepository) GetMessages(conversationID int) ([]Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored := r.messages[conversationID]
+	messages := make([]Message, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		messages = append(messages, stored[i])
+	}
+	return messages, nil
+}
+
+// MarkRead marks the messages sent to userID in the conversation as read and
+// returns how many were unread.
+func (r *memoryRepository) MarkRead(conversationID, userID int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.conversations[conversationID]
+	if !ok || !c.Has(userID) {
+		return 0, ErrConversationNotFound
+	}
+
+	count := 0
+	messages := r.messages[conversationID]
+	for i := range messages {
+		if messages[i].RecipientID == userID && !messages[i].Read {
+			messages[i].Read = true
+			count++
+		}
+	}
+	if c.LastMessage != nil && c.LastMessage.RecipientID == userID {
+		last := *c.LastMessage
+		last.Read = true
+		c.LastMessage = &last
+	}
+	return count, nil
+}
+
+func (r *memoryRepository) UnreadCount(userID int) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, c := range r.conversations {
+		if c.Has(userID) {
+			count += r.unread(c.ID, userID)
+		}
+	}
+	return count, nil
+}
+
+// view returns a copy of c with Unread counted for userID.
+func (r *memoryRepository) view(c *Conversation, userID int) Conversation {
+	v := *c
+	v.Unread = r.unread(c.ID, userID)
+	return v
+}
+
+func (r *memoryRepository) unread(conversationID, userID int) int {
+	count := 0
+	for _, m := range r.messages[conversationID] {
+		if m.RecipientID == userID && !m.Read {
+			count++
+		}
+	}
+	return count
+}
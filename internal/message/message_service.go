@@ -0,0 +1,101 @@
+package message
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"redditclone/internal/block"
+	"redditclone/internal/events"
+	"redditclone/internal/user"
+)
+
+const maxTextLength = 10000
+
+var (
+	ErrRecipientNotFound = errors.New("recipient not found")
+	ErrMessageSelf       = errors.New("you cannot message yourself")
+	ErrEmptyMessage      = errors.New("message text is required")
+	ErrMessageTooLong    = errors.New("message must be at most 10000 characters")
+	ErrBlocked           = errors.New("you cannot message this user")
+	ErrRecipientBlocked  = errors.New("unblock this user to message them")
+)
+
+type messageService struct {
+	repo         Repository
+	userService  user.Service
+	blockService block.Service
+	bus          *events.Bus
+}
+
+// NewMessageService sends messages between users who have not blocked each
+// other and publishes every sent message as MessageSent.
+func NewMessageService(repo Repository, userService user.Service, blockService block.Service, bus *events.Bus) Service {
+	return &messageService{
+		repo:         repo,
+		userService:  userService,
+		blockService: blockService,
+		bus:          bus,
+	}
+}
+
+func (s *messageService) Send(senderID int, recipient string, text string) (Message, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Message{}, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(text) > maxTextLength {
+		return Message{}, ErrMessageTooLong
+	}
+
+	to, err := s.userService.GetUserByUsername(recipient)
+	if err != nil {
+		return Message{}, ErrRecipientNotFound
+	}
+	if to.ID == senderID {
+		return Message{}, ErrMessageSelf
+	}
+	if s.blockService.HasBlocked(to.ID, senderID) {
+		return Message{}, ErrBlocked
+	}
+	if s.blockService.HasBlocked(senderID, to.ID) {
+		return Message{}, ErrRecipientBlocked
+	}
+
+	c, err := s.repo.GetOrCreateConversation(senderID, to.ID)
+	if err != nil {
+		return Message{}, err
+	}
+
+	m, err := s.repo.AddMessage(Message{
+		ConversationID: c.ID,
+		SenderID:       senderID,
+		RecipientID:    to.ID,
+		Text:           text,
+	})
+	if err != nil {
+		return Message{}, err
+	}
+
+	s.bus.Publish(events.MessageSent, m)
+	return m, nil
+}
+
+func (s *messageService) GetConversations(userID int) ([]Conversation, error) {
+	return s.repo.GetConversations(userID)
+}
+
+func (s *messageService) GetMessages(userID, conversationID int) ([]Message, error) {
+	if _, err := s.repo.GetConversation(conversationID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetMessages(conversationID)
+}
+
+func (s *messageService) MarkRead(userID, conversationID int) (int, error) {
+	return s.repo.MarkRead(conversationID, userID)
+}
+
+func (s *messageService) UnreadCount(userID int) (int, error) {
+	return s.repo.UnreadCount(userID)
+}
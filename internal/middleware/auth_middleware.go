@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strings"
 
 	"redditclone/internal/utils"
 )
@@ -26,3 +27,25 @@ func JWTMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		next(w, r.WithContext(ctx))
 	}
 }
+
+// OptionalJWTMiddleware identifies the user when a valid token is sent but
+// lets anonymous requests through, for public pages that are personalised
+// for logged-in users. A missing or invalid token leaves userID unset.
+func OptionalJWTMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			next(w, r)
+			return
+		}
+
+		userID, err := utils.ParseJWT(authHeader[len("Bearer "):])
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "userID", userID)
+		next(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,92 @@
+package moderation
+
+import (
+	"time"
+
+	"redditclone/internal/comment"
+	"redditclone/internal/post"
+)
+
+const (
+	ItemPost    = "post"
+	ItemComment = "comment"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRemoved  = "removed"
+	StatusIgnored  = "ignored"
+)
+
+const (
+	ActionApprove = "approve"
+	ActionRemove  = "remove"
+	ActionIgnore  = "ignore"
+
+	ActionAddModerator    = "add_moderator"
+	ActionRemoveModerator = "remove_moderator"
+	ActionLock            = "lock"
+	ActionUnlock          = "unlock"
+	ActionPin             = "pin"
+	ActionUnpin           = "unpin"
+	ActionFlair           = "flair"
+	ActionMark            = "mark"
+)
+
+// Report is one user's complaint. Reporters stay anonymous to moderators.
+type Report struct {
+	ReporterID int       `json:"-"`
+	Reason     string    `json:"reason"`
+	Created    time.Time `json:"created"`
+}
+
+// QueueItem collects the reports on a post or comment. It is pending until a
+// moderator acts on it; new reports put an approved item back in the queue,
+// while an ignored item stays ignored. Filtered content is held out of sight
+// of everyone but its author and the moderators until a moderator acts.
+type QueueItem struct {
+	ID        int              `json:"id"`
+	Type      string           `json:"type"`
+	Category  string           `json:"category"`
+	PostID    int              `json:"post_id"`
+	CommentID int              `json:"comment_id,omitempty"`
+	Status    string           `json:"status"`
+	Filtered  bool             `json:"filtered"`
+	Reports   []Report         `json:"reports"`
+	Created   time.Time        `json:"created"`
+	Post      *post.Post       `json:"post,omitempty"`
+	Comment   *comment.Comment `json:"comment,omitempty"`
+}
+
+// Action is the payload of events.ModeratorAction, published for everything
+// a moderator does. The target is a post, a comment (PostID and CommentID)
+// or a user (TargetUserID). Private actions, such as shadowbans, must not be
+// shown to anyone but the category's moderators.
+type Action struct {
+	Category     string
+	Action       string
+	ModeratorID  int
+	PostID       int
+	CommentID    int
+	TargetUserID int
+	Reason       string
+	Private      bool
+	Created      time.Time
+}
+
+// ReportEvent is the payload of events.ContentReported. Count is the number
+// of reports on the content so far.
+type ReportEvent struct {
+	Category   string
+	PostID     int
+	CommentID  int
+	ReporterID int
+	Count      int
+}
+
+// ActionResult reports the outcome of a moderator action on one queue item.
+type ActionResult struct {
+	ID    int    `json:"id"`
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,265 @@
+package moderation
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"redditclone/internal/post"
+	"redditclone/internal/utils"
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewModerationHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+type ReportRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ActionRequest struct {
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+	IDs    []int  `json:"ids"`
+}
+
+func (h *Handler) ReportPost(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Reporting a post")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["postID"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ReportPost(userID, postID, req.Reason); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ReportComment(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Reporting a comment")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	postID, err := strconv.Atoi(vars["postID"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(vars["commentID"])
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ReportComment(userID, postID, commentID, req.Reason); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting moderation queue")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, err := utils.ParsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, err := h.service.GetQueue(userID, mux.Vars(r)["category"], r.URL.Query().Get("status"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	start, end := page.Bounds(len(items))
+	utils.SetTotal(w, len(items))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(items[start:end]); err != nil {
+		http.Error(w, "Failed to encode queue", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Act applies one action to any number of queue items and reports the
+// outcome for each of them.
+func (h *Handler) Act(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Acting on moderation queue")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.IDs) == 0 {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.service.Act(userID, mux.Vars(r)["category"], req.Action, req.Reason, req.IDs)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		http.Error(w, "Failed to encode results", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) GetModerators(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting moderators")
+
+	moderators, err := h.service.GetModerators(mux.Vars(r)["category"])
+	if err != nil {
+		http.Error(w, "Could not retrieve moderators", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(moderators); err != nil {
+		http.Error(w, "Failed to encode moderators", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) AddModerator(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Adding a moderator")
+	h.manageModerator(w, r, h.service.AddModerator)
+}
+
+func (h *Handler) RemoveModerator(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Removing a moderator")
+	h.manageModerator(w, r, h.service.RemoveModerator)
+}
+
+func (h *Handler) manageModerator(w http.ResponseWriter, r *http.Request, action func(actorID int, category, username string) error) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := action(userID, vars["category"], vars["userLogin"]); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) LockPost(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Locking a post")
+	h.setPostState(w, r, true, h.service.SetLocked)
+}
+
+func (h *Handler) UnlockPost(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Unlocking a post")
+	h.setPostState(w, r, false, h.service.SetLocked)
+}
+
+func (h *Handler) PinPost(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Pinning a post")
+	h.setPostState(w, r, true, h.service.SetPinned)
+}
+
+func (h *Handler) UnpinPost(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Unpinning a post")
+	h.setPostState(w, r, false, h.service.SetPinned)
+}
+
+func (h *Handler) setPostState(w http.ResponseWriter, r *http.Request, on bool, action func(userID, postID int, on bool) (post.Post, error)) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["postID"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	p, err := action(userID, postID, on)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(p.Version))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		http.Error(w, "Failed to encode post", http.StatusInternalServerError)
+		return
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotAdmin), errors.Is(err, ErrNotModerator):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrAlreadyReported), errors.Is(err, ErrAlreadyModerator), errors.Is(err, ErrNotModeratorUser),
+		errors.Is(err, ErrPinLimit):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrEmptyReason), errors.Is(err, ErrReasonTooLong),
+		errors.Is(err, ErrUnknownAction), errors.Is(err, ErrUnknownStatus):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	}
+}
@@ -0,0 +1,30 @@
+package moderation
+
+import (
+	"redditclone/internal/comment"
+	"redditclone/internal/post"
+)
+
+type Service interface {
+	IsAdmin(userID int) bool
+	IsModerator(userID int, category string) bool
+	GetModerators(category string) ([]string, error)
+	AddModerator(actorID int, category, username string) error
+	RemoveModerator(actorID int, category, username string) error
+
+	SetLocked(userID, postID int, locked bool) (post.Post, error)
+	SetPinned(userID, postID int, pinned bool) (post.Post, error)
+	CommentGuard(action string, c comment.Comment, userID int) error
+
+	ReportPost(userID, postID int, reason string) error
+	ReportComment(userID, postID, commentID int, reason string) error
+	FilterPost(userID, postID int, reason string) error
+	FilterComment(userID, postID, commentID int, reason string) error
+	ReportCount(postID, commentID int) int
+	GetQueue(userID int, category, status string) ([]QueueItem, error)
+	Act(userID int, category, action, reason string, itemIDs []int) ([]ActionResult, error)
+
+	HideFilteredPosts(viewerID int, posts []post.Post) []post.Post
+	HideFilteredComments(viewerID int, p post.Post, comments []comment.Comment) []comment.Comment
+	HideFilteredUserComments(viewerID int, comments []comment.Comment) []comment.Comment
+}
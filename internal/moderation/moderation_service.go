@@ -0,0 +1,605 @@
+package moderation
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"redditclone/internal/comment"
+	"redditclone/internal/events"
+	"redditclone/internal/post"
+	"redditclone/internal/user"
+)
+
+const maxReasonLength = 500
+
+var (
+	ErrNotAdmin          = errors.New("only administrators can manage moderators")
+	ErrNotModerator      = errors.New("you are not a moderator of this category")
+	ErrAlreadyModerator  = errors.New("user is already a moderator of this category")
+	ErrNotModeratorUser  = errors.New("user is not a moderator of this category")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrEmptyReason       = errors.New("a reason is required")
+	ErrReasonTooLong     = errors.New("reason must be at most 500 characters")
+	ErrAlreadyReported   = errors.New("you have already reported this")
+	ErrUnknownAction     = errors.New("action must be one of approve, remove or ignore")
+	ErrUnknownStatus     = errors.New("status must be one of pending, approved, removed or ignored")
+	ErrQueueItemNotFound = errors.New("queue item not found")
+	ErrPostLocked        = errors.New("this post is locked")
+	ErrPinLimit          = errors.New("the category already has as many pinned posts as allowed")
+)
+
+type itemKey struct {
+	postID    int
+	commentID int
+}
+
+type moderationService struct {
+	mu         sync.Mutex
+	admins     map[string]bool
+	moderators map[string]map[int]bool
+	items      map[int]*QueueItem
+	byContent  map[itemKey]int
+	nextID     int
+	pinLimit   int
+
+	postService    post.Service
+	commentService comment.Service
+	userService    user.Service
+	bus            *events.Bus
+}
+
+// NewModerationService treats the users named in admins as moderators of
+// every category; they appoint the moderators of individual categories, who
+// may pin up to pinLimit posts each. Every moderator action is published as
+// ModeratorAction and every report as ContentReported.
+func NewModerationService(admins []string, pinLimit int, postService post.Service, commentService comment.Service, userService user.Service, bus *events.Bus) Service {
+	s := &moderationService{
+		admins:         make(map[string]bool, len(admins)),
+		moderators:     make(map[string]map[int]bool),
+		items:          make(map[int]*QueueItem),
+		byContent:      make(map[itemKey]int),
+		nextID:         1,
+		pinLimit:       pinLimit,
+		postService:    postService,
+		commentService: commentService,
+		userService:    userService,
+		bus:            bus,
+	}
+	for _, name := range admins {
+		s.admins[name] = true
+	}
+
+	bus.Subscribe(events.PostPurged, s.postPurged)
+	bus.Subscribe(events.CommentPurged, s.commentPurged)
+
+	return s
+}
+
+// IsAdmin looks the user up on every call, so admins may register after the
+// server has started.
+func (s *moderationService) IsAdmin(userID int) bool {
+	u, err := s.userService.GetUserByID(userID)
+	if err != nil {
+		return false
+	}
+	return s.admins[u.Username]
+}
+
+func (s *moderationService) IsModerator(userID int, category string) bool {
+	s.mu.Lock()
+	moderator := s.moderators[category][userID]
+	s.mu.Unlock()
+
+	return moderator || s.IsAdmin(userID)
+}
+
+// GetModerators returns the usernames of the category's own moderators,
+// sorted; admins are not listed.
+func (s *moderationService) GetModerators(category string) ([]string, error) {
+	s.mu.Lock()
+	ids := make([]int, 0, len(s.moderators[category]))
+	for id := range s.moderators[category] {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	names := []string{}
+	for _, id := range ids {
+		if u, err := s.userService.GetUserByID(id); err == nil {
+			names = append(names, u.Username)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *moderationService) AddModerator(actorID int, category, username string) error {
+	if !s.IsAdmin(actorID) {
+		return ErrNotAdmin
+	}
+	u, err := s.userService.GetUserByUsername(username)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	s.mu.Lock()
+	if s.moderators[category][u.ID] {
+		s.mu.Unlock()
+		return ErrAlreadyModerator
+	}
+	if s.moderators[category] == nil {
+		s.moderators[category] = make(map[int]bool)
+	}
+	s.moderators[category][u.ID] = true
+	s.mu.Unlock()
+
+	s.publish(Action{Category: category, Action: ActionAddModerator, ModeratorID: actorID, TargetUserID: u.ID})
+	return nil
+}
+
+func (s *moderationService) RemoveModerator(actorID int, category, username string) error {
+	if !s.IsAdmin(actorID) {
+		return ErrNotAdmin
+	}
+	u, err := s.userService.GetUserByUsername(username)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	s.mu.Lock()
+	if !s.moderators[category][u.ID] {
+		s.mu.Unlock()
+		return ErrNotModeratorUser
+	}
+	delete(s.moderators[category], u.ID)
+	s.mu.Unlock()
+
+	s.publish(Action{Category: category, Action: ActionRemoveModerator, ModeratorID: actorID, TargetUserID: u.ID})
+	return nil
+}
+
+// SetLocked locks the post against new comments or unlocks it. Setting the
+// state the post is already in changes nothing and is not logged.
+func (s *moderationService) SetLocked(userID, postID int, locked bool) (post.Post, error) {
+	p, err := s.postService.GetPostByID(postID)
+	if err != nil {
+		return post.Post{}, err
+	}
+	if !s.IsModerator(userID, p.Category) {
+		return post.Post{}, ErrNotModerator
+	}
+	if p.Locked == locked {
+		return p, nil
+	}
+
+	p, err = s.postService.SetLocked(postID, locked)
+	if err != nil {
+		return post.Post{}, err
+	}
+
+	action := ActionUnlock
+	if locked {
+		action = ActionLock
+	}
+	s.publish(Action{Category: p.Category, Action: action, ModeratorID: userID, PostID: postID})
+	return p, nil
+}
+
+// SetPinned pins the post to the top of its category or unpins it.
+func (s *moderationService) SetPinned(userID, postID int, pinned bool) (post.Post, error) {
+	p, err := s.postService.GetPostByID(postID)
+	if err != nil {
+		return post.Post{}, err
+	}
+	if !s.IsModerator(userID, p.Category) {
+		return post.Post{}, ErrNotModerator
+	}
+	if p.Pinned == pinned {
+		return p, nil
+	}
+
+	// Only moderators pin posts, so holding s.mu while counting and pinning
+	// keeps two of them from both taking the last slot.
+	s.mu.Lock()
+	if pinned {
+		posts, err := s.postService.GetPostsByCategory(p.Category)
+		if err != nil {
+			s.mu.Unlock()
+			return post.Post{}, err
+		}
+		count := 0
+		for _, other := range posts {
+			if other.Pinned {
+				count++
+			}
+		}
+		if count >= s.pinLimit {
+			s.mu.Unlock()
+			return post.Post{}, ErrPinLimit
+		}
+	}
+	p, err = s.postService.SetPinned(postID, pinned)
+	s.mu.Unlock()
+	if err != nil {
+		return post.Post{}, err
+	}
+
+	action := ActionUnpin
+	if pinned {
+		action = ActionPin
+	}
+	s.publish(Action{Category: p.Category, Action: action, ModeratorID: userID, PostID: postID})
+	return p, nil
+}
+
+// CommentGuard keeps everyone but the category's moderators from commenting
+// on locked posts, and everyone from commenting or voting on comments of
+// archived posts.
+func (s *moderationService) CommentGuard(action string, c comment.Comment, userID int) error {
+	p, err := s.postService.GetPostByID(c.PostID)
+	if err != nil {
+		return nil
+	}
+	if p.Archived {
+		return post.ErrArchived
+	}
+	if action != comment.ActionCreate || !p.Locked || s.IsModerator(userID, p.Category) {
+		return nil
+	}
+	return ErrPostLocked
+}
+
+func (s *moderationService) ReportPost(userID, postID int, reason string) error {
+	target, err := s.postItem(postID)
+	if err != nil {
+		return err
+	}
+	return s.report(userID, target, reason)
+}
+
+func (s *moderationService) ReportComment(userID, postID, commentID int, reason string) error {
+	target, err := s.commentItem(postID, commentID)
+	if err != nil {
+		return err
+	}
+	return s.report(userID, target, reason)
+}
+
+// FilterPost reports the post like ReportPost and holds it until a moderator
+// acts on it.
+func (s *moderationService) FilterPost(userID, postID int, reason string) error {
+	target, err := s.postItem(postID)
+	if err != nil {
+		return err
+	}
+	target.Filtered = true
+	return s.report(userID, target, reason)
+}
+
+// FilterComment reports the comment like ReportComment and holds it until a
+// moderator acts on it.
+func (s *moderationService) FilterComment(userID, postID, commentID int, reason string) error {
+	target, err := s.commentItem(postID, commentID)
+	if err != nil {
+		return err
+	}
+	target.Filtered = true
+	return s.report(userID, target, reason)
+}
+
+func (s *moderationService) postItem(postID int) (QueueItem, error) {
+	p, err := s.postService.GetPostByID(postID)
+	if err != nil {
+		return QueueItem{}, err
+	}
+	return QueueItem{
+		Type:     ItemPost,
+		Category: p.Category,
+		PostID:   postID,
+	}, nil
+}
+
+func (s *moderationService) commentItem(postID, commentID int) (QueueItem, error) {
+	p, err := s.postService.GetPostByID(postID)
+	if err != nil {
+		return QueueItem{}, err
+	}
+	if _, err := s.commentService.GetComment(postID, commentID); err != nil {
+		return QueueItem{}, err
+	}
+	return QueueItem{
+		Type:      ItemComment,
+		Category:  p.Category,
+		PostID:    postID,
+		CommentID: commentID,
+	}, nil
+}
+
+// ReportCount returns how many users have reported the post, or the comment
+// when commentID is not 0.
+func (s *moderationService) ReportCount(postID, commentID int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.byContent[itemKey{postID: postID, commentID: commentID}]
+	if !ok {
+		return 0
+	}
+	return len(s.items[id].Reports)
+}
+
+// GetQueue returns the category's items with the given status, pending by
+// default, oldest first. Items whose content is gone are left out.
+func (s *moderationService) GetQueue(userID int, category, status string) ([]QueueItem, error) {
+	if status == "" {
+		status = StatusPending
+	}
+	switch status {
+	case StatusPending, StatusApproved, StatusRemoved, StatusIgnored:
+	default:
+		return nil, ErrUnknownStatus
+	}
+	if !s.IsModerator(userID, category) {
+		return nil, ErrNotModerator
+	}
+
+	s.mu.Lock()
+	stored := []QueueItem{}
+	for _, item := range s.items {
+		if item.Category == category && item.Status == status {
+			copied := *item
+			copied.Reports = append([]Report(nil), item.Reports...)
+			stored = append(stored, copied)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(stored, func(i, j int) bool { return stored[i].ID < stored[j].ID })
+
+	items := []QueueItem{}
+	for _, item := range stored {
+		if status != StatusRemoved && !s.attach(&item) {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Act applies action to each of the items. Items fail individually, for
+// instance when they belong to another category, without stopping the rest.
+func (s *moderationService) Act(userID int, category, action, reason string, itemIDs []int) ([]ActionResult, error) {
+	switch action {
+	case ActionApprove, ActionRemove, ActionIgnore:
+	default:
+		return nil, ErrUnknownAction
+	}
+	if utf8.RuneCountInString(reason) > maxReasonLength {
+		return nil, ErrReasonTooLong
+	}
+	if !s.IsModerator(userID, category) {
+		return nil, ErrNotModerator
+	}
+
+	results := make([]ActionResult, 0, len(itemIDs))
+	for _, id := range itemIDs {
+		result := ActionResult{ID: id}
+		if err := s.act(userID, id, category, action, reason); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *moderationService) act(moderatorID, id int, category, action, reason string) error {
+	s.mu.Lock()
+	item, ok := s.items[id]
+	if !ok || item.Category != category {
+		s.mu.Unlock()
+		return ErrQueueItemNotFound
+	}
+	target := *item
+	s.mu.Unlock()
+
+	status := StatusApproved
+	switch action {
+	case ActionRemove:
+		status = StatusRemoved
+		var err error
+		if target.Type == ItemPost {
+			_, err = s.postService.RemovePost(target.PostID)
+		} else {
+			_, err = s.commentService.RemoveComment(target.PostID, target.CommentID)
+		}
+		if err != nil {
+			return err
+		}
+	case ActionIgnore:
+		status = StatusIgnored
+	}
+
+	s.mu.Lock()
+	if item, ok := s.items[id]; ok {
+		item.Status = status
+		item.Filtered = false
+	}
+	s.mu.Unlock()
+
+	s.publish(Action{
+		Category:    category,
+		Action:      action,
+		ModeratorID: moderatorID,
+		PostID:      target.PostID,
+		CommentID:   target.CommentID,
+		Reason:      reason,
+	})
+	return nil
+}
+
+func (s *moderationService) report(userID int, target QueueItem, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return ErrEmptyReason
+	}
+	if utf8.RuneCountInString(reason) > maxReasonLength {
+		return ErrReasonTooLong
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	key := itemKey{postID: target.PostID, commentID: target.CommentID}
+	item, ok := s.items[s.byContent[key]]
+	if !ok {
+		item = &target
+		item.ID = s.nextID
+		item.Status = StatusPending
+		item.Created = now
+		s.nextID++
+		s.items[item.ID] = item
+		s.byContent[key] = item.ID
+	}
+
+	duplicate := false
+	for _, r := range item.Reports {
+		if r.ReporterID == userID {
+			duplicate = true
+			break
+		}
+	}
+	if duplicate && !target.Filtered {
+		s.mu.Unlock()
+		return ErrAlreadyReported
+	}
+
+	if !duplicate {
+		item.Reports = append(item.Reports, Report{ReporterID: userID, Reason: reason, Created: now})
+	}
+	if item.Status == StatusApproved {
+		item.Status = StatusPending
+	}
+	// Content a moderator chose to ignore is not held again.
+	if target.Filtered && item.Status == StatusPending {
+		item.Filtered = true
+	}
+	if duplicate {
+		s.mu.Unlock()
+		return nil
+	}
+	reported := ReportEvent{
+		Category:   item.Category,
+		PostID:     item.PostID,
+		CommentID:  item.CommentID,
+		ReporterID: userID,
+		Count:      len(item.Reports),
+	}
+	s.mu.Unlock()
+
+	s.bus.Publish(events.ContentReported, reported)
+	return nil
+}
+
+// HideFilteredPosts drops held posts for everyone but their authors and the
+// category's moderators.
+func (s *moderationService) HideFilteredPosts(viewerID int, posts []post.Post) []post.Post {
+	visible := make([]post.Post, 0, len(posts))
+	for _, p := range posts {
+		if s.held(viewerID, p.AuthorID, p.Category, itemKey{postID: p.ID}) {
+			continue
+		}
+		visible = append(visible, p)
+	}
+	return visible
+}
+
+func (s *moderationService) HideFilteredComments(viewerID int, p post.Post, comments []comment.Comment) []comment.Comment {
+	visible := make([]comment.Comment, 0, len(comments))
+	for _, c := range comments {
+		if s.held(viewerID, c.AuthorID, p.Category, itemKey{postID: p.ID, commentID: c.ID}) {
+			continue
+		}
+		visible = append(visible, c)
+	}
+	return visible
+}
+
+func (s *moderationService) HideFilteredUserComments(viewerID int, comments []comment.Comment) []comment.Comment {
+	visible := make([]comment.Comment, 0, len(comments))
+	for _, c := range comments {
+		var category string
+		if p, err := s.postService.GetPostByID(c.PostID); err == nil {
+			category = p.Category
+		}
+		if s.held(viewerID, c.AuthorID, category, itemKey{postID: c.PostID, commentID: c.ID}) {
+			continue
+		}
+		visible = append(visible, c)
+	}
+	return visible
+}
+
+func (s *moderationService) held(viewerID, authorID int, category string, key itemKey) bool {
+	s.mu.Lock()
+	item, ok := s.items[s.byContent[key]]
+	filtered := ok && item.Filtered
+	s.mu.Unlock()
+
+	if !filtered || (viewerID != 0 && viewerID == authorID) {
+		return false
+	}
+	return viewerID == 0 || !s.IsModerator(viewerID, category)
+}
+
+func (s *moderationService) publish(action Action) {
+	action.Created = time.Now()
+	s.bus.Publish(events.ModeratorAction, action)
+}
+
+// attach fills in the reported content and reports false if it has been
+// deleted in the meantime.
+func (s *moderationService) attach(item *QueueItem) bool {
+	p, err := s.postService.GetPostByID(item.PostID)
+	if err != nil {
+		return false
+	}
+	item.Post = &p
+
+	if item.Type == ItemComment {
+		c, err := s.commentService.GetComment(item.PostID, item.CommentID)
+		if err != nil {
+			return false
+		}
+		item.Comment = &c
+	}
+	return true
+}
+
+func (s *moderationService) postPurged(e events.Event) {
+	if p, ok := e.Payload.(post.Post); ok {
+		s.drop(func(item *QueueItem) bool { return item.PostID == p.ID })
+	}
+}
+
+func (s *moderationService) commentPurged(e events.Event) {
+	if c, ok := e.Payload.(comment.Comment); ok {
+		s.drop(func(item *QueueItem) bool {
+			return item.Type == ItemComment && item.CommentID == c.ID
+		})
+	}
+}
+
+func (s *moderationService) drop(match func(*QueueItem) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, item := range s.items {
+		if match(item) {
+			delete(s.items, id)
+			delete(s.byContent, itemKey{postID: item.PostID, commentID: item.CommentID})
+		}
+	}
+}
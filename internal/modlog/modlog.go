@@ -0,0 +1,27 @@
+package modlog
+
+import "time"
+
+// Entry is a recorded moderator action. Moderator is empty when the category
+// redacts moderator identities and the viewer is not one of its moderators.
+// Private entries are listed to the category's moderators only.
+type Entry struct {
+	ID          int       `json:"id"`
+	Category    string    `json:"category"`
+	Action      string    `json:"action"`
+	ModeratorID int       `json:"moderator_id,omitempty"`
+	Moderator   string    `json:"moderator,omitempty"`
+	PostID      int       `json:"post_id,omitempty"`
+	CommentID   int       `json:"comment_id,omitempty"`
+	TargetUser  string    `json:"target_user,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	Created     time.Time `json:"created"`
+
+	targetUserID int
+	private      bool
+}
+
+// Settings control how a category's log is shown to the public.
+type Settings struct {
+	RedactModerators bool `json:"redact_moderators"`
+}
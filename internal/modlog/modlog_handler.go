@@ -0,0 +1,99 @@
+package modlog
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"redditclone/internal/moderation"
+	"redditclone/internal/utils"
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewModlogHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetLog is public; logged-in moderators of the category also see redacted
+// moderator names.
+func (h *Handler) GetLog(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting moderator log")
+
+	userID, _ := r.Context().Value("userID").(int)
+
+	page, err := utils.ParsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	entries, err := h.service.GetLog(userID, mux.Vars(r)["category"], query.Get("action"), query.Get("moderator"))
+	if err != nil {
+		if errors.Is(err, ErrModeratorsRedacted) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Could not retrieve moderator log", http.StatusInternalServerError)
+		return
+	}
+
+	start, end := page.Bounds(len(entries))
+	utils.SetTotal(w, len(entries))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries[start:end]); err != nil {
+		http.Error(w, "Failed to encode moderator log", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting moderator log settings")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.service.GetSettings(mux.Vars(r)["category"])); err != nil {
+		http.Error(w, "Failed to encode settings", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Updating moderator log settings")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var settings Settings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdateSettings(userID, mux.Vars(r)["category"], settings); err != nil {
+		if errors.Is(err, moderation.ErrNotModerator) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Could not update settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(settings); err != nil {
+		http.Error(w, "Failed to encode settings", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,9 @@
+package modlog
+
+type Service interface {
+	// GetLog returns the category's entries, newest first, optionally
+	// limited to one action and one moderator's username.
+	GetLog(viewerID int, category, action, moderator string) ([]Entry, error)
+	GetSettings(category string) Settings
+	UpdateSettings(userID int, category string, settings Settings) error
+}
@@ -0,0 +1,139 @@
+package modlog
+
+import (
+	"errors"
+	"sync"
+
+	"redditclone/internal/events"
+	"redditclone/internal/moderation"
+	"redditclone/internal/user"
+)
+
+var ErrModeratorsRedacted = errors.New("moderator identities are hidden in this category")
+
+type modlogService struct {
+	mu                sync.RWMutex
+	entries           map[string][]Entry
+	settings          map[string]Settings
+	nextID            int
+	moderationService moderation.Service
+	userService       user.Service
+}
+
+// NewModlogService records every ModeratorAction in its category's log.
+// Entries are only ever appended.
+func NewModlogService(moderationService moderation.Service, userService user.Service, bus *events.Bus) Service {
+	s := &modlogService{
+		entries:           make(map[string][]Entry),
+		settings:          make(map[string]Settings),
+		nextID:            1,
+		moderationService: moderationService,
+		userService:       userService,
+	}
+
+	bus.Subscribe(events.ModeratorAction, s.record)
+
+	return s
+}
+
+func (s *modlogService) GetLog(viewerID int, category, action, moderator string) ([]Entry, error) {
+	isModerator := s.moderationService.IsModerator(viewerID, category)
+	redact := s.GetSettings(category).RedactModerators && !isModerator
+	if redact && moderator != "" {
+		return nil, ErrModeratorsRedacted
+	}
+
+	moderatorID := 0
+	if moderator != "" {
+		u, err := s.userService.GetUserByUsername(moderator)
+		if err != nil {
+			return []Entry{}, nil
+		}
+		moderatorID = u.ID
+	}
+
+	s.mu.RLock()
+	stored := s.entries[category]
+	entries := []Entry{}
+	for i := len(stored) - 1; i >= 0; i-- {
+		e := stored[i]
+		if e.private && !isModerator {
+			continue
+		}
+		if action != "" && e.Action != action {
+			continue
+		}
+		if moderatorID != 0 && e.ModeratorID != moderatorID {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	s.mu.RUnlock()
+
+	for i := range entries {
+		s.resolve(&entries[i], redact)
+	}
+	return entries, nil
+}
+
+func (s *modlogService) GetSettings(category string) Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.settings[category]
+}
+
+func (s *modlogService) UpdateSettings(userID int, category string, settings Settings) error {
+	if !s.moderationService.IsModerator(userID, category) {
+		return moderation.ErrNotModerator
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.settings[category] = settings
+	return nil
+}
+
+func (s *modlogService) record(e events.Event) {
+	action, ok := e.Payload.(moderation.Action)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[action.Category] = append(s.entries[action.Category], Entry{
+		ID:           s.nextID,
+		Category:     action.Category,
+		Action:       action.Action,
+		ModeratorID:  action.ModeratorID,
+		PostID:       action.PostID,
+		CommentID:    action.CommentID,
+		Reason:       action.Reason,
+		Created:      action.Created,
+		targetUserID: action.TargetUserID,
+		private:      action.Private,
+	})
+	s.nextID++
+}
+
+// resolve fills in usernames. When redacted, the moderator is left out, and
+// so is the target of appointments, which would name a moderator too.
+func (s *modlogService) resolve(e *Entry, redact bool) {
+	if redact {
+		e.ModeratorID = 0
+		if e.Action == moderation.ActionAddModerator || e.Action == moderation.ActionRemoveModerator {
+			return
+		}
+	} else if u, err := s.userService.GetUserByID(e.ModeratorID); err == nil {
+		e.Moderator = u.Username
+	}
+
+	if e.targetUserID != 0 {
+		if u, err := s.userService.GetUserByID(e.targetUserID); err == nil {
+			e.TargetUser = u.Username
+		}
+	}
+}
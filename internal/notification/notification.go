@@ -0,0 +1,56 @@
+package notification
+
+import "time"
+
+const (
+	TypePostReply    = "post_reply"
+	TypeCommentReply = "comment_reply"
+	TypeMention      = "mention"
+	TypeMilestone    = "milestone"
+)
+
+// Settings say which types of notification a user receives.
+type Settings struct {
+	PostReplies    bool `json:"post_replies"`
+	CommentReplies bool `json:"comment_replies"`
+	Mentions       bool `json:"mentions"`
+	Milestones     bool `json:"milestones"`
+}
+
+// DefaultSettings turn every type on.
+var DefaultSettings = Settings{PostReplies: true, CommentReplies: true, Mentions: true, Milestones: true}
+
+// Allows reports whether notifications of type kind are on.
+func (s Settings) Allows(kind string) bool {
+	switch kind {
+	case TypePostReply:
+		return s.PostReplies
+	case TypeCommentReply:
+		return s.CommentReplies
+	case TypeMention:
+		return s.Mentions
+	case TypeMilestone:
+		return s.Milestones
+	}
+	return true
+}
+
+// SettingsSource reports a user's notification settings.
+type SettingsSource interface {
+	NotificationSettings(userID int) Settings
+}
+
+// Notification tells user UserID about activity on their content. ActorID is the
+// user who caused it and is empty for score milestones; CommentID is set when
+// the notification is about a comment.
+type Notification struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Type      string    `json:"type"`
+	ActorID   int       `json:"actor_id,omitempty"`
+	PostID    int       `json:"post_id"`
+	CommentID int       `json:"comment_id,omitempty"`
+	Score     int       `json:"score,omitempty"`
+	Read      bool      `json:"read"`
+	Created   time.Time `json:"created"`
+}
@@ -0,0 +1,110 @@
+package notification
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"redditclone/internal/utils"
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewNotificationHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting notifications")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, err := utils.ParsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notifications, err := h.service.GetNotifications(userID, r.URL.Query().Get("unread") == "true")
+	if err != nil {
+		http.Error(w, "Could not retrieve notifications", http.StatusInternalServerError)
+		return
+	}
+
+	start, end := page.Bounds(len(notifications))
+	utils.SetTotal(w, len(notifications))
+	w.Header().Set("X-Unread-Count", strconv.Itoa(h.service.UnreadCount(userID)))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(notifications[start:end]); err != nil {
+		http.Error(w, "Failed to encode notifications", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting unread notification count")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]int{"unread": h.service.UnreadCount(userID)}); err != nil {
+		http.Error(w, "Failed to encode unread count", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Marking notification as read")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	notificationID, err := strconv.Atoi(mux.Vars(r)["notificationID"])
+	if err != nil {
+		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.MarkRead(userID, notificationID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Marking all notifications as read")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]int{"marked": h.service.MarkAllRead(userID)}); err != nil {
+		http.Error(w, "Failed to encode result", http.StatusInternalServerError)
+		return
+	}
+}
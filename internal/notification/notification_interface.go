@@ -0,0 +1,12 @@
+package notification
+
+import "redditclone/internal/post"
+
+type Service interface {
+	AddPostFilter(filter post.ListFilter)
+	AddCommentFilter(filter post.CommentFilter)
+	GetNotifications(userID int, unreadOnly bool) ([]Notification, error)
+	UnreadCount(userID int) int
+	MarkRead(userID, notificationID int) error
+	MarkAllRead(userID int) int
+}
@@ -0,0 +1,318 @@
+package notification
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+
+	"redditclone/internal/block"
+	"redditclone/internal/comment"
+	"redditclone/internal/events"
+	"redditclone/internal/post"
+	"redditclone/internal/user"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// milestones are the scores at which authors hear about their content.
+var milestones = []int{10, 50, 100, 500, 1000, 5000, 10000}
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w-]+)`)
+
+// content identifies a post, or a comment when comment is set.
+type content struct {
+	comment bool
+	id      int
+}
+
+type notificationService struct {
+	mu             sync.Mutex
+	inbox          map[int][]Notification
+	nextID         int
+	reached        map[content]int
+	postService    post.Service
+	commentService comment.Service
+	userService    user.Service
+	blockService   block.Service
+	settings       SettingsSource
+	postFilters    []post.ListFilter
+	commentFilters []post.CommentFilter
+	bus            *events.Bus
+}
+
+// NewNotificationService fills inboxes from bus events: replies to a user's
+// post or comment, @username mentions in new posts and comments, and scores
+// crossing a milestone. Nothing is delivered from users the recipient has
+// blocked or of a type the recipient has turned off in settings. Every
+// delivered notification is also published as NotificationCreated.
+func NewNotificationService(postService post.Service, commentService comment.Service, userService user.Service, blockService block.Service, settings SettingsSource, bus *events.Bus) Service {
+	s := &notificationService{
+		inbox:          make(map[int][]Notification),
+		nextID:         1,
+		reached:        make(map[content]int),
+		postService:    postService,
+		commentService: commentService,
+		userService:    userService,
+		blockService:   blockService,
+		settings:       settings,
+		bus:            bus,
+	}
+
+	bus.Subscribe(events.PostCreated, s.postCreated)
+	bus.Subscribe(events.CommentCreated, s.commentCreated)
+	bus.Subscribe(events.PostVoted, s.postVoted)
+	bus.Subscribe(events.CommentVoted, s.commentVoted)
+	bus.Subscribe(events.PostPurged, s.postPurged)
+
+	return s
+}
+
+// AddPostFilter registers a listing filter that the post a mention comes
+// from must pass for the mentioned user to be notified.
+// Filters must be added before any content is created.
+func (s *notificationService) AddPostFilter(filter post.ListFilter) {
+	s.postFilters = append(s.postFilters, filter)
+}
+
+// AddCommentFilter does the same for the comment behind a reply or mention.
+func (s *notificationService) AddCommentFilter(filter post.CommentFilter) {
+	s.commentFilters = append(s.commentFilters, filter)
+}
+
+// GetNotifications returns the user's notifications, newest first.
+func (s *notificationService) GetNotifications(userID int, unreadOnly bool) ([]Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.inbox[userID]
+	notifications := []Notification{}
+	for i := len(stored) - 1; i >= 0; i-- {
+		if unreadOnly && stored[i].Read {
+			continue
+		}
+		notifications = append(notifications, stored[i])
+	}
+
+	return notifications, nil
+}
+
+func (s *notificationService) UnreadCount(userID int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, n := range s.inbox[userID] {
+		if !n.Read {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *notificationService) MarkRead(userID, notificationID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, n := range s.inbox[userID] {
+		if n.ID == notificationID {
+			s.inbox[userID][i].Read = true
+			return nil
+		}
+	}
+	return ErrNotificationNotFound
+}
+
+// MarkAllRead marks every notification as read and returns how many were
+// unread.
+func (s *notificationService) MarkAllRead(userID int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for i := range s.inbox[userID] {
+		if !s.inbox[userID][i].Read {
+			s.inbox[userID][i].Read = true
+			count++
+		}
+	}
+	return count
+}
+
+func (s *notificationService) postCreated(e events.Event) {
+	p, ok := e.Payload.(post.Post)
+	if !ok {
+		return
+	}
+
+	s.mentions(p.Title+"\n"+p.Text, Notification{
+		Type:    TypeMention,
+		ActorID: p.AuthorID,
+		PostID:  p.ID,
+	}, nil)
+}
+
+func (s *notificationService) commentCreated(e events.Event) {
+	c, ok := e.Payload.(comment.Comment)
+	if !ok {
+		return
+	}
+
+	reply := Notification{
+		ActorID:   c.AuthorID,
+		PostID:    c.PostID,
+		CommentID: c.ID,
+	}
+	recipient := 0
+	if c.ParentID != 0 {
+		if parent, err := s.commentService.GetComment(c.PostID, c.ParentID); err == nil {
+			reply.Type = TypeCommentReply
+			recipient = parent.AuthorID
+		}
+	} else if p, err := s.postService.GetPostByID(c.PostID); err == nil {
+		reply.Type = TypePostReply
+		recipient = p.AuthorID
+	}
+	if recipient != 0 {
+		s.deliver(recipient, reply)
+	}
+
+	// The author being replied to already hears about the comment, so a
+	// mention of them would only be a duplicate.
+	mention := reply
+	mention.Type = TypeMention
+	s.mentions(c.Text, mention, map[int]bool{recipient: true})
+}
+
+func (s *notificationService) postVoted(e events.Event) {
+	if vote, ok := e.Payload.(post.VoteEvent); ok {
+		s.milestone(content{id: vote.Post.ID}, vote.Post.AuthorID, vote.Post.Score(), Notification{
+			PostID: vote.Post.ID,
+		})
+	}
+}
+
+func (s *notificationService) commentVoted(e events.Event) {
+	if vote, ok := e.Payload.(comment.VoteEvent); ok {
+		s.milestone(content{comment: true, id: vote.Comment.ID}, vote.Comment.AuthorID, vote.Comment.Score(), Notification{
+			PostID:    vote.Comment.PostID,
+			CommentID: vote.Comment.ID,
+		})
+	}
+}
+
+// postPurged drops notifications that point at content which no longer
+// exists.
+func (s *notificationService) postPurged(e events.Event) {
+	p, ok := e.Payload.(post.Post)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for userID, notifications := range s.inbox {
+		kept := notifications[:0]
+		for _, n := range notifications {
+			if n.PostID != p.ID {
+				kept = append(kept, n)
+			}
+		}
+		s.inbox[userID] = kept
+	}
+}
+
+// mentions notifies every user named with @username in text, once each,
+// except the ones in skip.
+func (s *notificationService) mentions(text string, n Notification, skip map[int]bool) {
+	seen := make(map[int]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		u, err := s.userService.GetUserByUsername(match[1])
+		if err != nil || seen[u.ID] || skip[u.ID] {
+			continue
+		}
+		seen[u.ID] = true
+		s.deliver(u.ID, n)
+	}
+}
+
+// milestone notifies the author the first time the score reaches each
+// milestone. Falling back below one and rising again stays silent.
+func (s *notificationService) milestone(item content, authorID, score int, n Notification) {
+	s.mu.Lock()
+	reached := s.reached[item]
+	next := reached
+	for next < len(milestones) && score >= milestones[next] {
+		next++
+	}
+	s.reached[item] = next
+	s.mu.Unlock()
+
+	if next == reached {
+		return
+	}
+
+	n.Type = TypeMilestone
+	n.Score = milestones[next-1]
+	s.deliver(authorID, n)
+}
+
+// deliver adds n to the recipient's inbox unless it is about their own
+// activity, comes from a user they have blocked, is about content the
+// filters hide from them or is of a type they have turned off.
+func (s *notificationService) deliver(recipientID int, n Notification) {
+	if !s.settings.NotificationSettings(recipientID).Allows(n.Type) {
+		return
+	}
+	if n.ActorID != 0 {
+		if n.ActorID == recipientID || s.blockService.HasBlocked(recipientID, n.ActorID) {
+			return
+		}
+		if !s.visible(recipientID, n) {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	n.ID = s.nextID
+	n.UserID = recipientID
+	n.Created = time.Now()
+	s.nextID++
+	s.inbox[recipientID] = append(s.inbox[recipientID], n)
+	s.mu.Unlock()
+
+	s.bus.Publish(events.NotificationCreated, n)
+}
+
+// visible reports whether the post or comment that caused n passes the
+// filters for the recipient, so that, for example, a shadowbanned user's
+// replies and mentions reach nobody.
+func (s *notificationService) visible(recipientID int, n Notification) bool {
+	p, err := s.postService.GetPostByID(n.PostID)
+	if err != nil {
+		return false
+	}
+
+	if n.CommentID == 0 {
+		posts := []post.Post{p}
+		for _, filter := range s.postFilters {
+			if posts = filter(recipientID, posts); len(posts) == 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	c, err := s.commentService.GetComment(n.PostID, n.CommentID)
+	if err != nil {
+		return false
+	}
+	comments := []comment.Comment{c}
+	for _, filter := range s.commentFilters {
+		if comments = filter(recipientID, p, comments); len(comments) == 0 {
+			return false
+		}
+	}
+	return true
+}
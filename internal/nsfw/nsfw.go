@@ -0,0 +1,27 @@
+package nsfw
+
+// Modes say what happens to NSFW posts or spoilers for a viewer: shown as
+// they are, marked for the client to blur, or left out of listings and
+// search. A hidden post opened directly is blurred.
+const (
+	ModeShow = "show"
+	ModeBlur = "blur"
+	ModeHide = "hide"
+)
+
+// Settings are a viewer's preferences for NSFW posts and spoilers.
+type Settings struct {
+	NSFW     string `json:"nsfw"`
+	Spoilers string `json:"spoilers"`
+}
+
+// DefaultSettings apply to anonymous viewers and to users who have not
+// chosen otherwise.
+var DefaultSettings = Settings{NSFW: ModeHide, Spoilers: ModeBlur}
+
+// Marks are the flags an author or moderator puts on a post; nil leaves a
+// flag as it is.
+type Marks struct {
+	NSFW    *bool `json:"nsfw"`
+	Spoiler *bool `json:"spoiler"`
+}
@@ -0,0 +1,142 @@
+package nsfw
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"redditclone/internal/moderation"
+	"redditclone/internal/post"
+	"redditclone/internal/utils"
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewNSFWHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting content settings")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	h.writeSettings(w, h.service.GetSettings(userID))
+}
+
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Updating content settings")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var settings Settings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(userID, settings)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.writeSettings(w, settings)
+}
+
+func (h *Handler) writeSettings(w http.ResponseWriter, settings Settings) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(settings); err != nil {
+		http.Error(w, "Failed to encode settings", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) MarkCategory(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Marking a category NSFW")
+	h.setCategory(w, r, true)
+}
+
+func (h *Handler) UnmarkCategory(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Unmarking an NSFW category")
+	h.setCategory(w, r, false)
+}
+
+func (h *Handler) setCategory(w http.ResponseWriter, r *http.Request, nsfw bool) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.SetNSFWCategory(userID, mux.Vars(r)["category"], nsfw); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) MarkPost(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Marking a post")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["postID"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var marks Marks
+	if err := json.NewDecoder(r.Body).Decode(&marks); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.service.MarkPost(userID, postID, marks)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(p.Version))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		http.Error(w, "Failed to encode post", http.StatusInternalServerError)
+		return
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, moderation.ErrNotModerator), errors.Is(err, ErrNotAuthor):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, post.ErrPostNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
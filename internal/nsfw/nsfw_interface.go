@@ -0,0 +1,21 @@
+package nsfw
+
+import "redditclone/internal/post"
+
+type Service interface {
+	GetSettings(userID int) Settings
+	UpdateSettings(userID int, settings Settings) (Settings, error)
+
+	IsNSFWCategory(category string) bool
+	SetNSFWCategory(userID int, category string, nsfw bool) error
+	MarkPost(userID, postID int, marks Marks) (post.Post, error)
+
+	// Exclude is a post.ListFilter that drops the posts the viewer hides.
+	Exclude(viewerID int, posts []post.Post) []post.Post
+	// Mark is a view filter that flags posts in NSFW categories as NSFW and
+	// sets Blurred on the posts the viewer does not want shown as they are.
+	Mark(viewerID int, posts []post.Post) []post.Post
+	// ShowCategory reports whether the category may be suggested to the
+	// viewer: NSFW categories are not, unless the viewer shows or blurs NSFW.
+	ShowCategory(viewerID int, category string) bool
+}
@@ -0,0 +1,167 @@
+package nsfw
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"redditclone/internal/events"
+	"redditclone/internal/moderation"
+	"redditclone/internal/post"
+)
+
+var (
+	ErrInvalidMode = errors.New("mode must be one of show, blur or hide")
+	ErrNotAuthor   = errors.New("only the author or a moderator can mark this post")
+)
+
+type nsfwService struct {
+	mu         sync.RWMutex
+	settings   map[int]Settings
+	categories map[string]bool
+
+	postService       post.Service
+	moderationService moderation.Service
+	bus               *events.Bus
+}
+
+// NewNSFWService keeps the NSFW categories and every user's settings.
+// Moderators marking someone else's post is published as a ModeratorAction.
+func NewNSFWService(postService post.Service, moderationService moderation.Service, bus *events.Bus) Service {
+	return &nsfwService{
+		settings:          make(map[int]Settings),
+		categories:        make(map[string]bool),
+		postService:       postService,
+		moderationService: moderationService,
+		bus:               bus,
+	}
+}
+
+func (s *nsfwService) GetSettings(userID int) Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.settingsFor(userID)
+}
+
+func (s *nsfwService) UpdateSettings(userID int, settings Settings) (Settings, error) {
+	if !validMode(settings.NSFW) || !validMode(settings.Spoilers) {
+		return Settings{}, ErrInvalidMode
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.settings[userID] = settings
+	return settings, nil
+}
+
+func (s *nsfwService) IsNSFWCategory(category string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.categories[category]
+}
+
+func (s *nsfwService) SetNSFWCategory(userID int, category string, nsfw bool) error {
+	if !s.moderationService.IsModerator(userID, category) {
+		return moderation.ErrNotModerator
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if nsfw {
+		s.categories[category] = true
+	} else {
+		delete(s.categories, category)
+	}
+	return nil
+}
+
+func (s *nsfwService) MarkPost(userID, postID int, marks Marks) (post.Post, error) {
+	p, err := s.postService.GetPostByID(postID)
+	if err != nil {
+		return post.Post{}, err
+	}
+	if p.AuthorID != userID && !s.moderationService.IsModerator(userID, p.Category) {
+		return post.Post{}, ErrNotAuthor
+	}
+
+	nsfw, spoiler := p.NSFW, p.Spoiler
+	if marks.NSFW != nil {
+		nsfw = *marks.NSFW
+	}
+	if marks.Spoiler != nil {
+		spoiler = *marks.Spoiler
+	}
+
+	p, err = s.postService.SetMarks(postID, nsfw, spoiler)
+	if err != nil {
+		return post.Post{}, err
+	}
+
+	if p.AuthorID != userID {
+		s.bus.Publish(events.ModeratorAction, moderation.Action{
+			Category:    p.Category,
+			Action:      moderation.ActionMark,
+			ModeratorID: userID,
+			PostID:      postID,
+			Created:     time.Now(),
+		})
+	}
+	return p, nil
+}
+
+func (s *nsfwService) Exclude(viewerID int, posts []post.Post) []post.Post {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings := s.settingsFor(viewerID)
+	kept := posts[:0]
+	for _, p := range posts {
+		nsfw := p.NSFW || s.categories[p.Category]
+		if nsfw && settings.NSFW == ModeHide || p.Spoiler && settings.Spoilers == ModeHide {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept
+}
+
+func (s *nsfwService) Mark(viewerID int, posts []post.Post) []post.Post {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings := s.settingsFor(viewerID)
+	for i := range posts {
+		p := &posts[i]
+		p.NSFW = p.NSFW || s.categories[p.Category]
+		p.Blurred = p.NSFW && settings.NSFW != ModeShow || p.Spoiler && settings.Spoilers != ModeShow
+	}
+	return posts
+}
+
+func (s *nsfwService) ShowCategory(viewerID int, category string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return !s.categories[category] || s.settingsFor(viewerID).NSFW != ModeHide
+}
+
+// settingsFor returns the viewer's settings, the defaults for anonymous
+// viewers. The caller holds s.mu.
+func (s *nsfwService) settingsFor(userID int) Settings {
+	if settings, ok := s.settings[userID]; ok && userID != 0 {
+		return settings
+	}
+	return DefaultSettings
+}
+
+func validMode(mode string) bool {
+	switch mode {
+	case ModeShow, ModeBlur, ModeHide:
+		return true
+	}
+	return false
+}
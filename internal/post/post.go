@@ -1,16 +1,113 @@
 package post
 
-import "redditclone/internal/comment"
+import (
+	"time"
+
+	"redditclone/internal/comment"
+)
 
 type Post struct {
 	ID        int               `json:"id"`
 	Title     string            `json:"title,omitempty"`
 	URL       string            `json:"url,omitempty"`
 	Text      string            `json:"text,omitempty"`
+	Image     *Image            `json:"image,omitempty"`
 	Category  string            `json:"category"`
 	AuthorID  int               `json:"author_id"`
 	Comments  []comment.Comment `json:"comments"`
 	Upvotes   int               `json:"upvotes"`
 	Downvotes int               `json:"downvotes"`
-	Voters    map[int]int
+	Created   time.Time         `json:"created"`
+	Version   int               `json:"version"`
+	Collapsed bool              `json:"collapsed,omitempty"`
+	Locked    bool              `json:"locked,omitempty"`
+	Pinned    bool              `json:"pinned,omitempty"`
+	PinnedAt  time.Time         `json:"-"`
+	Archived  bool              `json:"archived,omitempty"`
+	NSFW      bool              `json:"nsfw,omitempty"`
+	Spoiler   bool              `json:"spoiler,omitempty"`
+	Blurred   bool              `json:"blurred,omitempty"`
+	Flair     *Flair            `json:"flair,omitempty"`
+	// AuthorFlair is the author's flair in the category, set when shown.
+	AuthorFlair *Flair    `json:"author_flair,omitempty"`
+	Deleted     bool      `json:"-"`
+	DeletedAt   time.Time `json:"-"`
+	Removed     bool      `json:"-"`
+}
+
+// Flair is a tag shown next to the post's title or its author's name.
+// TemplateID is 0 for flair not taken from a category's templates.
+type Flair struct {
+	TemplateID int    `json:"template_id,omitempty"`
+	Text       string `json:"text"`
+	Color      string `json:"color,omitempty"`
+}
+
+// Image is the picture of an image post with its thumbnails, smallest
+// first.
+type Image struct {
+	URL         string      `json:"url"`
+	ContentType string      `json:"content_type"`
+	Width       int         `json:"width"`
+	Height      int         `json:"height"`
+	Size        int64       `json:"size"`
+	Thumbnails  []Thumbnail `json:"thumbnails"`
+}
+
+// Thumbnail is a scaled-down copy of an Image. Name is the size it was made
+// for, such as "small".
+type Thumbnail struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Type reports whether the post is a link, image or text post.
+func (p Post) Type() string {
+	switch {
+	case p.URL != "":
+		return "link"
+	case p.Image != nil:
+		return "image"
+	}
+	return "text"
+}
+
+// Score is the post's rating: upvotes minus downvotes.
+func (p Post) Score() int {
+	return p.Upvotes - p.Downvotes
+}
+
+// VoteEvent is the payload of events.PostVoted. Previous and Vote are the
+// voter's old and new vote: 1, -1, or 0 for none.
+type VoteEvent struct {
+	Post     Post
+	UserID   int
+	Previous int
+	Vote     int
+}
+
+// Actions passed to a Guard.
+const (
+	ActionCreate = "create"
+	ActionVote   = "vote"
+)
+
+// Guard vets an action by userID on p before the service applies it; a
+// non-nil error rejects the action. For ActionCreate p is the post about to
+// be created.
+type Guard func(action string, p Post, userID int) error
+
+// RejectedError wraps the error of the Guard that refused an action.
+type RejectedError struct {
+	Err error
+}
+
+func (e *RejectedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RejectedError) Unwrap() error {
+	return e.Err
 }
@@ -0,0 +1,170 @@
+package post
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"redditclone/internal/events"
+)
+
+const (
+	benchPosts      = 100_000
+	benchCategories = 100
+	benchAuthors    = 1_000
+)
+
+// mutexRepository is the store posts were kept in before the indexes and
+// lock striping: one map behind one mutex, with listings scanning every post
+// and every vote copying the post's voters.
+type mutexRepository struct {
+	mu     sync.Mutex
+	posts  map[int]Post
+	voters map[int]map[int]int
+	nextID int
+}
+
+func newMutexRepository() *mutexRepository {
+	return &mutexRepository{posts: make(map[int]Post), voters: make(map[int]map[int]int), nextID: 1}
+}
+
+func (r *mutexRepository) create(post Post) Post {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post.ID = r.nextID
+	r.nextID++
+	r.posts[post.ID] = post
+	r.voters[post.ID] = make(map[int]int)
+	return post
+}
+
+func (r *mutexRepository) byCategory(category string) []Post {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	posts := make([]Post, 0, len(r.posts))
+	for _, post := range r.posts {
+		if post.Category == category && !post.Deleted {
+			posts = append(posts, post)
+		}
+	}
+	return posts
+}
+
+func (r *mutexRepository) byAuthor(authorID int) []Post {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	posts := make([]Post, 0, len(r.posts))
+	for _, post := range r.posts {
+		if post.AuthorID == authorID && !post.Deleted {
+			posts = append(posts, post)
+		}
+	}
+	return posts
+}
+
+func (r *mutexRepository) upvote(postID, userID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post := r.posts[postID]
+	voters := make(map[int]int, len(r.voters[postID])+1)
+	for id, vote := range r.voters[postID] {
+		voters[id] = vote
+	}
+	voters[userID] = 1
+	r.voters[postID] = voters
+	post.Upvotes++
+	r.posts[postID] = post
+}
+
+func (r *mutexRepository) delete(postID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post := r.posts[postID]
+	post.Deleted = true
+	r.posts[postID] = post
+}
+
+func newTestService() *postService {
+	logger := log.New(io.Discard, "", 0)
+	return NewPostService(logger, events.NewBus(), time.Minute, time.Hour).(*postService)
+}
+
+func testPost(i int) Post {
+	return Post{
+		Title:    fmt.Sprintf("post %d", i),
+		Text:     "text",
+		Category: fmt.Sprintf("category%d", i%benchCategories),
+		AuthorID: i%benchAuthors + 1,
+	}
+}
+
+func fillService(b *testing.B, n int) *postService {
+	b.Helper()
+	s := newTestService()
+	for i := 0; i < n; i++ {
+		if _, err := s.CreatePost(testPost(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return s
+}
+
+func fillRepository(n int) *mutexRepository {
+	r := newMutexRepository()
+	for i := 0; i < n; i++ {
+		r.create(testPost(i))
+	}
+	return r
+}
+
+func BenchmarkGetPostsByCategory(b *testing.B) {
+	s := fillService(b, benchPosts)
+	r := fillRepository(benchPosts)
+
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			posts, _ := s.GetPostsByCategory(fmt.Sprintf("category%d", i%benchCategories))
+			if len(posts) != benchPosts/benchCategories {
+				b.Fatalf("got %d posts", len(posts))
+			}
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			posts := r.byCategory(fmt.Sprintf("category%d", i%benchCategories))
+			if len(posts) != benchPosts/benchCategories {
+				b.Fatalf("got %d posts", len(posts))
+			}
+		}
+	})
+}
+
+func BenchmarkGetPostsByUser(b *testing.B) {
+	s := fillService(b, benchPosts)
+	r := fillRepository(benchPosts)
+
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			posts, _ := s.GetPostsByUser(i%benchAuthors + 1)
+			if len(posts) != benchPosts/benchAuthors {
+				b.Fatalf("got %d posts", len(posts))
+			}
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			posts := r.byAuthor(i%benchAuthors + 1)
+			if len(posts) != benchPosts/benchAuthors {
+				b.Fatalf("got %d posts", len(posts))
+			}
+		}
+	})
+}
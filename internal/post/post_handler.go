@@ -2,27 +2,108 @@ package post
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 
+	"redditclone/internal/comment"
 	"redditclone/internal/user"
+	"redditclone/internal/utils"
 )
 
+// ListFilter drops the posts a viewer should not see from a listing, or
+// adjusts how they are shown. viewerID is 0 for anonymous requests.
+type ListFilter func(viewerID int, posts []Post) []Post
+
+// CommentFilter does the same for the comments shown with post.
+type CommentFilter func(viewerID int, post Post, comments []comment.Comment) []comment.Comment
+
+// maxUploadSize bounds the whole multipart form of an image post: the image
+// itself, which the ImageStore limits further, and the other fields.
+const maxUploadSize = 12 << 20
+
+// ImageStore processes and stores the pictures of image posts.
+type ImageStore interface {
+	Upload(data []byte) (Image, error)
+	Delete(image Image)
+}
+
+// SortPreference returns the listing order a viewer prefers, or "" for
+// the default.
+type SortPreference func(viewerID int) string
+
+// FlairResolver returns the flair userID may give a new post in category
+// from one of its templates.
+type FlairResolver func(userID int, category string, templateID int) (*Flair, error)
+
 type Handler struct {
-	postService Service
-	userService user.Service
-	logger      *log.Logger
+	postService    Service
+	userService    user.Service
+	commentService comment.Service
+	listFilters    []ListFilter
+	viewFilters    []ListFilter
+	commentFilters []CommentFilter
+	resolveFlair   FlairResolver
+	preferredSort  SortPreference
+	images         ImageStore
+	logger         *log.Logger
 }
 
-func NewPostHandler(postService Service, userService user.Service, logger *log.Logger) *Handler {
+func NewPostHandler(postService Service, userService user.Service, commentService comment.Service, logger *log.Logger) *Handler {
 	return &Handler{
-		postService: postService,
-		userService: userService,
-		logger:      logger,
+		postService:    postService,
+		userService:    userService,
+		commentService: commentService,
+		logger:         logger,
+	}
+}
+
+// AddListFilter appends a step to the pipeline that GetAllPosts and
+// GetPostsByCategory run before paginating.
+func (h *Handler) AddListFilter(filter ListFilter) {
+	h.listFilters = append(h.listFilters, filter)
+}
+
+// AddViewFilter appends a step that runs wherever posts are shown: in every
+// listing and on the post page, which answers 404 if the filter drops the
+// post.
+func (h *Handler) AddViewFilter(filter ListFilter) {
+	h.viewFilters = append(h.viewFilters, filter)
+}
+
+// SetFlairResolver lets CreatePost accept a flair template. Without one,
+// posts created with a flair are rejected.
+func (h *Handler) SetFlairResolver(resolve FlairResolver) {
+	h.resolveFlair = resolve
+}
+
+// SetImageStore enables CreateImagePost.
+func (h *Handler) SetImageStore(images ImageStore) {
+	h.images = images
+}
+
+// SetSortPreference lets listings without ?sort= use the viewer's own
+// default order.
+func (h *Handler) SetSortPreference(preferredSort SortPreference) {
+	h.preferredSort = preferredSort
+}
+
+// AddCommentFilter appends a step run over the comments on the post page.
+func (h *Handler) AddCommentFilter(filter CommentFilter) {
+	h.commentFilters = append(h.commentFilters, filter)
+}
+
+func (h *Handler) applyViewFilters(viewerID int, posts []Post) []Post {
+	for _, filter := range h.viewFilters {
+		posts = filter(viewerID, posts)
 	}
+	return posts
 }
 
 type CreatePostRequest struct {
@@ -30,6 +111,9 @@ type CreatePostRequest struct {
 	URL      string `json:"url,omitempty"`
 	Text     string `json:"text,omitempty"`
 	Category string `json:"category"`
+	FlairID  int    `json:"flair_id,omitempty"`
+	NSFW     bool   `json:"nsfw,omitempty"`
+	Spoiler  bool   `json:"spoiler,omitempty"`
 }
 
 func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) {
@@ -47,20 +131,117 @@ func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.createPost(w, userID, req, nil)
+}
+
+// CreateImagePost takes a multipart form with the fields of
+// CreatePostRequest, except url, and the picture in the "image" part.
+func (h *Handler) CreateImagePost(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Creating a new image post")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if h.images == nil {
+		http.Error(w, "Image posts are not supported", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Upload is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	req := CreatePostRequest{
+		Title:    r.FormValue("title"),
+		Text:     r.FormValue("text"),
+		Category: r.FormValue("category"),
+		NSFW:     r.FormValue("nsfw") == "true",
+		Spoiler:  r.FormValue("spoiler") == "true",
+	}
+	if value := r.FormValue("flair_id"); value != "" {
+		flairID, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid flair ID", http.StatusBadRequest)
+			return
+		}
+		req.FlairID = flairID
+	}
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		http.Error(w, "Missing image", http.StatusBadRequest)
+		return
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		http.Error(w, "Could not read image", http.StatusBadRequest)
+		return
+	}
+
+	image, err := h.images.Upload(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.createPost(w, userID, req, &image)
+}
+
+// createPost creates the post described by req and writes it out. If that
+// fails, the image uploaded for it, if any, is deleted again.
+func (h *Handler) createPost(w http.ResponseWriter, userID int, req CreatePostRequest, image *Image) {
 	post := Post{
 		Title:    req.Title,
 		URL:      req.URL,
 		Text:     req.Text,
+		Image:    image,
 		Category: req.Category,
 		AuthorID: userID,
+		NSFW:     req.NSFW,
+		Spoiler:  req.Spoiler,
+	}
+
+	if req.FlairID != 0 {
+		if h.resolveFlair == nil {
+			h.discardImage(image)
+			http.Error(w, "Flair is not supported", http.StatusBadRequest)
+			return
+		}
+		flair, err := h.resolveFlair(userID, req.Category, req.FlairID)
+		if err != nil {
+			h.discardImage(image)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		post.Flair = flair
 	}
 
 	createdPost, err := h.postService.CreatePost(post)
 	if err != nil {
+		h.discardImage(image)
+
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	w.Header().Set("ETag", utils.ETag(createdPost.Version))
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(createdPost); err != nil {
 		http.Error(w, "Failed to encode post", http.StatusInternalServerError)
@@ -68,6 +249,12 @@ func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *Handler) discardImage(image *Image) {
+	if image != nil {
+		h.images.Delete(*image)
+	}
+}
+
 func (h *Handler) GetAllPosts(w http.ResponseWriter, r *http.Request) {
 	h.logger.Println("Getting all posts")
 
@@ -77,11 +264,7 @@ func (h *Handler) GetAllPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(posts); err != nil {
-		http.Error(w, "Failed to encode posts", http.StatusInternalServerError)
-		return
-	}
+	h.writeListing(w, r, posts, false)
 }
 
 func (h *Handler) GetPostsByCategory(w http.ResponseWriter, r *http.Request) {
@@ -96,8 +279,67 @@ func (h *Handler) GetPostsByCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if flair := r.URL.Query().Get("flair"); flair != "" {
+		posts = withFlair(posts, flair)
+	}
+
+	h.writeListing(w, r, posts, true)
+}
+
+// withFlair keeps the posts whose flair text is flair, ignoring case.
+func withFlair(posts []Post, flair string) []Post {
+	kept := posts[:0]
+	for _, p := range posts {
+		if p.Flair != nil && strings.EqualFold(p.Flair.Text, flair) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// writeListing runs posts through the list filters for the requesting user,
+// sorts them and writes the requested page. Filtering comes first so that
+// X-Total-Count matches what the user can actually page through. With pinned
+// set, pinned posts lead the listing, earliest pinned first.
+func (h *Handler) writeListing(w http.ResponseWriter, r *http.Request, posts []Post, pinned bool) {
+	page, err := utils.ParsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewerID, _ := r.Context().Value("userID").(int)
+	order := r.URL.Query().Get("sort")
+	if order == "" && h.preferredSort != nil {
+		order = h.preferredSort(viewerID)
+	}
+	if order == "" {
+		order = SortNew
+	}
+	if !ValidSort(order) {
+		http.Error(w, "sort must be one of hot, new, top or old", http.StatusBadRequest)
+		return
+	}
+
+	for _, filter := range h.listFilters {
+		posts = filter(viewerID, posts)
+	}
+	posts = h.applyViewFilters(viewerID, posts)
+
+	SortPosts(posts, order)
+	if pinned {
+		sort.SliceStable(posts, func(i, j int) bool {
+			if posts[i].Pinned != posts[j].Pinned {
+				return posts[i].Pinned
+			}
+			return posts[i].Pinned && posts[i].PinnedAt.Before(posts[j].PinnedAt)
+		})
+	}
+
+	start, end := page.Bounds(len(posts))
+	utils.SetTotal(w, len(posts))
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(posts); err != nil {
+	if err := json.NewEncoder(w).Encode(posts[start:end]); err != nil {
 		http.Error(w, "Failed to encode posts", http.StatusInternalServerError)
 		return
 	}
@@ -119,6 +361,25 @@ func (h *Handler) GetPostDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	viewerID, _ := r.Context().Value("userID").(int)
+	visible := h.applyViewFilters(viewerID, []Post{post})
+	if len(visible) == 0 {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	post = visible[0]
+
+	comments, err := h.commentService.GetCommentsByPost(postID)
+	if err != nil {
+		http.Error(w, "Could not retrieve comments", http.StatusInternalServerError)
+		return
+	}
+	for _, filter := range h.commentFilters {
+		comments = filter(viewerID, post, comments)
+	}
+	post.Comments = comments
+
+	w.Header().Set("ETag", utils.ETag(post.Version))
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(post); err != nil {
 		http.Error(w, "Failed to encode post", http.StatusInternalServerError)
@@ -142,13 +403,24 @@ func (h *Handler) DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postService.DeletePost(postID, userID)
+	version, err := utils.IfMatchVersion(r)
+	if err != nil {
+		utils.WritePreconditionError(w, err)
+		return
+	}
+
+	err = h.postService.DeletePost(postID, userID, version)
 	if err != nil {
-		if err.Error() == "not authorized to delete this post" {
+		if errors.Is(err, ErrNotAuthorized) {
 			http.Error(w, err.Error(), http.StatusForbidden)
 			return
 		}
 
+		if errors.Is(err, ErrVersionConflict) {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
@@ -156,6 +428,48 @@ func (h *Handler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handler) RestorePost(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Restoring post")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	postID, err := strconv.Atoi(vars["postID"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	version, err := utils.IfMatchVersion(r)
+	if err != nil {
+		utils.WritePreconditionError(w, err)
+		return
+	}
+
+	err = h.postService.RestorePost(postID, userID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrVersionConflict):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		case errors.Is(err, ErrRestoreNotOwner), errors.Is(err, ErrRemoved):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, ErrRestoreExpired):
+			http.Error(w, err.Error(), http.StatusGone)
+		case errors.Is(err, ErrNotDeleted):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *Handler) UpvotePost(w http.ResponseWriter, r *http.Request) {
 	h.logger.Println("Upvoting a post")
 
@@ -172,12 +486,19 @@ func (h *Handler) UpvotePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postService.UpvotePost(postID, userID)
+	version, err := utils.IfMatchVersion(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		utils.WritePreconditionError(w, err)
+		return
+	}
+
+	post, err := h.postService.UpvotePost(postID, userID, version)
+	if err != nil {
+		h.writeVoteError(w, err)
 		return
 	}
 
+	w.Header().Set("ETag", utils.ETag(post.Version))
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -197,12 +518,19 @@ func (h *Handler) DownvotePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postService.DownvotePost(postID, userID)
+	version, err := utils.IfMatchVersion(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		utils.WritePreconditionError(w, err)
 		return
 	}
 
+	post, err := h.postService.DownvotePost(postID, userID, version)
+	if err != nil {
+		h.writeVoteError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(post.Version))
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -222,25 +550,60 @@ func (h *Handler) UnvotePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postService.UnvotePost(postID, userID)
+	version, err := utils.IfMatchVersion(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		utils.WritePreconditionError(w, err)
+		return
+	}
+
+	post, err := h.postService.UnvotePost(postID, userID, version)
+	if err != nil {
+		h.writeVoteError(w, err)
 		return
 	}
 
+	w.Header().Set("ETag", utils.ETag(post.Version))
 	w.WriteHeader(http.StatusOK)
 }
 
-func (s *postService) GetPostsByUser(userID int) ([]Post, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (h *Handler) writeVoteError(w http.ResponseWriter, err error) {
+	var rejected *RejectedError
+	switch {
+	case errors.As(err, &rejected), errors.Is(err, ErrArchived):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrPostNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
 
-	userPosts := []Post{}
-	for _, post := range s.posts {
-		if post.AuthorID == userID {
-			userPosts = append(userPosts, post)
-		}
+func (h *Handler) GetPostsByUser(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting posts by user")
+
+	vars := mux.Vars(r)
+	userLogin := vars["userLogin"]
+
+	user, err := h.userService.GetUserByUsername(userLogin)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
 	}
 
-	return userPosts, nil
+	posts, err := h.postService.GetPostsByUser(user.ID)
+	if err != nil {
+		http.Error(w, "Could not retrieve posts", http.StatusInternalServerError)
+		return
+	}
+
+	viewerID, _ := r.Context().Value("userID").(int)
+	posts = h.applyViewFilters(viewerID, posts)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(posts); err != nil {
+		http.Error(w, "Failed to encode posts", http.StatusInternalServerError)
+		return
+	}
 }
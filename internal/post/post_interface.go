@@ -1,13 +1,25 @@
 package post
 
+import "time"
+
 type Service interface {
+	AddGuard(guard Guard)
 	CreatePost(post Post) (Post, error)
 	GetAllPosts() ([]Post, error)
 	GetPostsByCategory(category string) ([]Post, error)
 	GetPostByID(id int) (Post, error)
-	DeletePost(postID, userID int) error
-	UpvotePost(postID, userID int) error
-	DownvotePost(postID, userID int) error
-	UnvotePost(postID, userID int) error
+	PostExists(id int) bool
+	DeletePost(postID, userID, version int) error
+	RestorePost(postID, userID, version int) error
+	RemovePost(postID int) (Post, error)
+	SetLocked(postID int, locked bool) (Post, error)
+	SetPinned(postID int, pinned bool) (Post, error)
+	SetFlair(postID int, flair *Flair) (Post, error)
+	SetMarks(postID int, nsfw, spoiler bool) (Post, error)
+	ArchiveOlderThan(age time.Duration) []int
+	PurgeDeleted() []int
+	UpvotePost(postID, userID, version int) (Post, error)
+	DownvotePost(postID, userID, version int) (Post, error)
+	UnvotePost(postID, userID, version int) (Post, error)
 	GetPostsByUser(userID int) ([]Post, error)
 }
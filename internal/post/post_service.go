@@ -1,170 +1,448 @@
 package post
 
 import (
-	"encoding/json"
 	"errors"
 	"log"
-	"net/http"
-	"sync"
-
-	"github.com/gorilla/mux"
+	"sync/atomic"
+	"time"
 
 	"redditclone/internal/comment"
+	"redditclone/internal/events"
+	"redditclone/internal/utils"
+)
+
+var (
+	ErrPostNotFound    = errors.New("post not found")
+	ErrNotAuthorized   = errors.New("not authorized to delete this post")
+	ErrNotDeleted      = errors.New("post is not deleted")
+	ErrRestoreExpired  = errors.New("restore window has expired")
+	ErrRestoreNotOwner = errors.New("not authorized to restore this post")
+	ErrVersionConflict = errors.New("post was modified concurrently")
+	ErrRemoved         = errors.New("post was removed by a moderator")
+	ErrArchived        = errors.New("post is archived")
+
+	// errUnchanged tells update to leave a post as it is.
+	errUnchanged = errors.New("post unchanged")
 )
 
+// postService keeps posts in a lock-striped store. Every mutation locks the
+// post it touches, publishes a new immutable Post value and swaps it into the
+// listings the post is in. Listings are read from immutable snapshots, so
+// readers never wait for writers, and writers to different posts share only
+// the brief swap of a listing's root.
 type postService struct {
-	mu     sync.Mutex
-	posts  map[int]Post
-	nextID int
-	logger *log.Logger
+	posts         *store
+	all           *listing
+	byCategory    index[string]
+	byAuthor      index[int]
+	lastID        atomic.Int64
+	restoreWindow time.Duration
+	retention     time.Duration
+	guards        []Guard
+	bus           *events.Bus
+	logger        *log.Logger
 }
 
-func NewPostService(logger *log.Logger) Service {
+// NewPostService keeps deleted posts as tombstones: their authors may restore
+// them during restoreWindow, and PurgeDeleted drops them for good once
+// retention has passed.
+func NewPostService(logger *log.Logger, bus *events.Bus, restoreWindow, retention time.Duration) Service {
 	return &postService{
-		posts:  make(map[int]Post),
-		nextID: 1,
-		logger: logger,
+		posts:         newStore(),
+		all:           newListing(),
+		restoreWindow: restoreWindow,
+		retention:     retention,
+		bus:           bus,
+		logger:        logger,
 	}
 }
 
+// AddGuard registers a check run before posts are created or voted on.
+// Guards must be added before the service starts handling requests.
+func (s *postService) AddGuard(guard Guard) {
+	s.guards = append(s.guards, guard)
+}
+
 func (s *postService) CreatePost(post Post) (Post, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if err := s.check(ActionCreate, post, post.AuthorID); err != nil {
+		return Post{}, err
+	}
 
-	post.ID = s.nextID
-	s.nextID++
+	post.ID = int(s.lastID.Add(1))
 	post.Comments = []comment.Comment{}
-	post.Voters = make(map[int]int)
-	s.posts[post.ID] = post
+	post.Version = 1
+	post.Created = time.Now()
+
+	e := &entry{voters: make(map[int]int)}
+	e.post.Store(&post)
+
+	e.mu.Lock()
+	s.posts.put(post.ID, e)
+	s.index(&post)
+	e.mu.Unlock()
 
 	s.logger.Printf("Post created: %+v\n", post)
+	s.bus.Publish(events.PostCreated, post)
 	return post, nil
 }
 
+// Listings are read from a single snapshot: a listing shows every post as it
+// was at one instant, never some posts from before a write and some from
+// after it.
 func (s *postService) GetAllPosts() ([]Post, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	posts := make([]Post, 0, len(s.posts))
-	for _, post := range s.posts {
-		posts = append(posts, post)
-	}
-
-	return posts, nil
+	return s.all.snapshot().posts(), nil
 }
 
 func (s *postService) GetPostsByCategory(category string) ([]Post, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	posts := make([]Post, 0, len(s.posts))
-	for _, post := range s.posts {
-		if post.Category == category {
-			posts = append(posts, post)
-		}
-	}
+	return s.byCategory.snapshot(category).posts(), nil
+}
 
-	return posts, nil
+func (s *postService) GetPostsByUser(userID int) ([]Post, error) {
+	return s.byAuthor.snapshot(userID).posts(), nil
 }
 
 func (s *postService) GetPostByID(id int) (Post, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	post, exists := s.posts[id]
+	e, exists := s.posts.get(id)
 	if !exists {
-		return Post{}, errors.New("post not found")
+		return Post{}, ErrPostNotFound
+	}
+
+	post := e.load()
+	if post.Deleted {
+		return Post{}, ErrPostNotFound
 	}
 
 	return post, nil
 }
 
-func (s *postService) DeletePost(postID, userID int) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// PostExists reports whether GetPostByID would find the post.
+func (s *postService) PostExists(id int) bool {
+	_, err := s.GetPostByID(id)
+	return err == nil
+}
 
-	post, exists := s.posts[postID]
-	if !exists {
-		return errors.New("post not found")
-	}
+func (s *postService) DeletePost(postID, userID, version int) error {
+	post, err := s.update(postID, func(post *Post) error {
+		if post.Deleted {
+			return ErrPostNotFound
+		}
+		if post.AuthorID != userID {
+			return ErrNotAuthorized
+		}
+		if !versionMatches(*post, version) {
+			return ErrVersionConflict
+		}
 
-	if post.AuthorID != userID {
-		return errors.New("not authorized to delete this post")
+		post.Deleted = true
+		post.DeletedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	delete(s.posts, postID)
 	s.logger.Printf("Post deleted: %d\n", postID)
+	s.bus.Publish(events.PostDeleted, post)
 	return nil
 }
 
-func (s *postService) UpvotePost(postID, userID int) error {
-	post, err := s.GetPostByID(postID)
+func (s *postService) RestorePost(postID, userID, version int) error {
+	post, err := s.update(postID, func(post *Post) error {
+		if !post.Deleted {
+			return ErrNotDeleted
+		}
+		if post.AuthorID != userID {
+			return ErrRestoreNotOwner
+		}
+		if post.Removed {
+			return ErrRemoved
+		}
+		if time.Since(post.DeletedAt) > s.restoreWindow {
+			return ErrRestoreExpired
+		}
+		if !versionMatches(*post, version) {
+			return ErrVersionConflict
+		}
+
+		post.Deleted = false
+		post.DeletedAt = time.Time{}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	if currentVote, exists := post.Voters[userID]; exists && currentVote == 1 {
-		return errors.New("already upvoted")
-	}
-
-	post.Upvotes++
-	post.Voters[userID] = 1
-	s.posts[postID] = post
+	s.logger.Printf("Post restored: %d\n", postID)
+	s.bus.Publish(events.PostRestored, post)
 	return nil
 }
 
-func (s *postService) DownvotePost(postID, userID int) error {
-	post, err := s.GetPostByID(postID)
+// RemovePost soft-deletes a post on behalf of a moderator. Unlike a post its
+// author deleted, a removed post cannot be restored by the author.
+func (s *postService) RemovePost(postID int) (Post, error) {
+	post, err := s.update(postID, func(post *Post) error {
+		if post.Deleted {
+			return ErrPostNotFound
+		}
+
+		post.Deleted = true
+		post.DeletedAt = time.Now()
+		post.Removed = true
+		return nil
+	})
 	if err != nil {
-		return err
+		return Post{}, err
 	}
 
-	if currentVote, exists := post.Voters[userID]; exists && currentVote == -1 {
-		return errors.New("already downvoted")
+	s.logger.Printf("Post removed: %d\n", postID)
+	s.bus.Publish(events.PostDeleted, post)
+	return post, nil
+}
+
+// SetLocked locks the post against new comments or unlocks it.
+func (s *postService) SetLocked(postID int, locked bool) (Post, error) {
+	return s.update(postID, func(post *Post) error {
+		if post.Deleted {
+			return ErrPostNotFound
+		}
+		post.Locked = locked
+		return nil
+	})
+}
+
+// SetPinned pins the post to the top of its category listing or unpins it.
+func (s *postService) SetPinned(postID int, pinned bool) (Post, error) {
+	return s.update(postID, func(post *Post) error {
+		if post.Deleted {
+			return ErrPostNotFound
+		}
+		post.Pinned = pinned
+		post.PinnedAt = time.Time{}
+		if pinned {
+			post.PinnedAt = time.Now()
+		}
+		return nil
+	})
+}
+
+// SetFlair replaces the post's flair; nil clears it.
+func (s *postService) SetFlair(postID int, flair *Flair) (Post, error) {
+	return s.update(postID, func(post *Post) error {
+		if post.Deleted {
+			return ErrPostNotFound
+		}
+		post.Flair = flair
+		return nil
+	})
+}
+
+// SetMarks sets the post's NSFW and spoiler flags.
+func (s *postService) SetMarks(postID int, nsfw, spoiler bool) (Post, error) {
+	return s.update(postID, func(post *Post) error {
+		if post.Deleted {
+			return ErrPostNotFound
+		}
+		post.NSFW = nsfw
+		post.Spoiler = spoiler
+		return nil
+	})
+}
+
+// ArchiveOlderThan archives live posts created more than age ago and returns
+// their IDs. Archived posts accept no more votes or comments.
+func (s *postService) ArchiveOlderThan(age time.Duration) []int {
+	cutoff := time.Now().Add(-age)
+
+	var archived []int
+	for _, post := range s.all.snapshot().posts() {
+		if post.Archived || !post.Created.Before(cutoff) {
+			continue
+		}
+
+		_, err := s.update(post.ID, func(post *Post) error {
+			if post.Deleted || post.Archived {
+				return errUnchanged
+			}
+			post.Archived = true
+			return nil
+		})
+		if err == nil {
+			archived = append(archived, post.ID)
+		}
 	}
 
-	post.Downvotes++
-	post.Voters[userID] = -1
-	s.posts[postID] = post
-	return nil
+	if len(archived) > 0 {
+		s.logger.Printf("Archived %d posts\n", len(archived))
+	}
+	return archived
 }
 
-func (s *postService) UnvotePost(postID, userID int) error {
-	post, err := s.GetPostByID(postID)
-	if err != nil {
-		return err
+// PurgeDeleted permanently removes tombstones older than the retention period
+// and returns the IDs of the purged posts.
+func (s *postService) PurgeDeleted() []int {
+	cutoff := time.Now().Add(-s.retention)
+	removed := s.posts.removeIf(func(post Post) bool {
+		return post.Deleted && post.DeletedAt.Before(cutoff)
+	})
+
+	purged := make([]int, 0, len(removed))
+	for _, post := range removed {
+		purged = append(purged, post.ID)
+		s.bus.Publish(events.PostPurged, post)
 	}
 
-	if _, exists := post.Voters[userID]; !exists {
-		return errors.New("no vote to remove")
+	if len(purged) > 0 {
+		s.logger.Printf("Purged %d deleted posts\n", len(purged))
 	}
+	return purged
+}
 
-	delete(post.Voters, userID)
-	s.posts[postID] = post
-	return nil
+func (s *postService) UpvotePost(postID, userID, version int) (Post, error) {
+	return s.vote(postID, userID, version, 1)
 }
 
-func (h *Handler) GetPostsByUser(w http.ResponseWriter, r *http.Request) {
-	h.logger.Println("Getting posts by user")
+func (s *postService) DownvotePost(postID, userID, version int) (Post, error) {
+	return s.vote(postID, userID, version, -1)
+}
 
-	vars := mux.Vars(r)
-	userLogin := vars["userLogin"]
+func (s *postService) UnvotePost(postID, userID, version int) (Post, error) {
+	return s.vote(postID, userID, version, 0)
+}
 
-	user, err := h.userService.GetUserByUsername(userLogin)
+// vote replaces userID's vote on the post with value (1, -1, or 0 to remove
+// it) if the post is still at the expected version.
+func (s *postService) vote(postID, userID, version, value int) (Post, error) {
+	target, err := s.GetPostByID(postID)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
+		return Post{}, err
+	}
+	if err := s.check(ActionVote, target, userID); err != nil {
+		return Post{}, err
+	}
+
+	e, exists := s.posts.get(postID)
+	if !exists {
+		return Post{}, ErrPostNotFound
 	}
 
-	posts, err := h.postService.GetPostsByUser(user.ID)
+	var previous int
+	post, err := s.apply(e, func(post *Post) error {
+		if post.Deleted {
+			return ErrPostNotFound
+		}
+		if post.Archived {
+			return ErrArchived
+		}
+
+		current, voted := e.voters[userID]
+		switch {
+		case value == 1 && current == 1:
+			return errors.New("already upvoted")
+		case value == -1 && current == -1:
+			return errors.New("already downvoted")
+		case value == 0 && !voted:
+			return errors.New("no vote to remove")
+		}
+
+		if !versionMatches(*post, version) {
+			return ErrVersionConflict
+		}
+
+		switch current {
+		case 1:
+			post.Upvotes--
+		case -1:
+			post.Downvotes--
+		}
+		switch value {
+		case 1:
+			post.Upvotes++
+			e.voters[userID] = value
+		case -1:
+			post.Downvotes++
+			e.voters[userID] = value
+		default:
+			delete(e.voters, userID)
+		}
+
+		previous = current
+		return nil
+	})
 	if err != nil {
-		http.Error(w, "Could not retrieve posts", http.StatusInternalServerError)
-		return
+		return Post{}, err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(posts); err != nil {
-		http.Error(w, "Failed to encode posts", http.StatusInternalServerError)
-		return
+	s.bus.Publish(events.PostVoted, VoteEvent{
+		Post:     post,
+		UserID:   userID,
+		Previous: previous,
+		Vote:     value,
+	})
+	return post, nil
+}
+
+// check runs the guards outside of any lock, so they are free to query
+// this and other services.
+func (s *postService) check(action string, post Post, userID int) error {
+	for _, guard := range s.guards {
+		if err := guard(action, post, userID); err != nil {
+			return &RejectedError{Err: err}
+		}
 	}
+	return nil
+}
+
+// update applies fn to a copy of the post under the post's own lock and, if
+// fn succeeds, publishes the copy with its version bumped.
+func (s *postService) update(postID int, fn func(post *Post) error) (Post, error) {
+	e, exists := s.posts.get(postID)
+	if !exists {
+		return Post{}, ErrPostNotFound
+	}
+	return s.apply(e, fn)
+}
+
+// apply is update for an entry the caller has already looked up. fn runs
+// with e.mu held, so it may also change e.voters. The new version replaces
+// the old one in the listings; posts moving into the deleted state are
+// dropped from them, so listings never have to skip tombstones.
+func (s *postService) apply(e *entry, fn func(post *Post) error) (Post, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	post := e.load()
+	wasDeleted := post.Deleted
+	if err := fn(&post); err != nil {
+		return Post{}, err
+	}
+
+	post.Version++
+	e.post.Store(&post)
+
+	switch {
+	case !post.Deleted:
+		s.index(&post)
+	case !wasDeleted:
+		s.unindex(post)
+	}
+	return post, nil
+}
+
+// index adds the post to its listings or replaces its previous version in
+// them. The caller holds the post's entry lock, so the listings receive a
+// post's versions in order.
+func (s *postService) index(post *Post) {
+	s.all.set(post)
+	s.byCategory.listing(post.Category).set(post)
+	s.byAuthor.listing(post.AuthorID).set(post)
+}
+
+func (s *postService) unindex(post Post) {
+	s.all.remove(post.ID)
+	s.byCategory.listing(post.Category).remove(post.ID)
+	s.byAuthor.listing(post.AuthorID).remove(post.ID)
+}
+
+func versionMatches(post Post, version int) bool {
+	return version == utils.AnyVersion || version == post.Version
 }
@@ -0,0 +1,69 @@
+package post
+
+import (
+	"math"
+	"sort"
+)
+
+// Listing orders.
+const (
+	SortHot = "hot"
+	SortNew = "new"
+	SortTop = "top"
+	SortOld = "old"
+)
+
+// ValidSort reports whether order is one of the listing orders.
+func ValidSort(order string) bool {
+	switch order {
+	case SortHot, SortNew, SortTop, SortOld:
+		return true
+	}
+	return false
+}
+
+// SortPosts orders posts in place. Ties go to the newer post.
+func SortPosts(posts []Post, order string) {
+	newer := func(i, j int) bool {
+		if !posts[i].Created.Equal(posts[j].Created) {
+			return posts[i].Created.After(posts[j].Created)
+		}
+		return posts[i].ID > posts[j].ID
+	}
+
+	switch order {
+	case SortNew:
+		sort.SliceStable(posts, newer)
+	case SortOld:
+		sort.SliceStable(posts, func(i, j int) bool { return newer(j, i) })
+	case SortTop:
+		sort.SliceStable(posts, func(i, j int) bool {
+			if si, sj := posts[i].Score(), posts[j].Score(); si != sj {
+				return si > sj
+			}
+			return newer(i, j)
+		})
+	case SortHot:
+		sort.SliceStable(posts, func(i, j int) bool {
+			if hi, hj := hotness(posts[i]), hotness(posts[j]); hi != hj {
+				return hi > hj
+			}
+			return newer(i, j)
+		})
+	}
+}
+
+// hotness weighs the order of magnitude of a post's score against its age,
+// so that every 12.5 hours a post needs ten times the score to stay level.
+func hotness(p Post) float64 {
+	score := p.Score()
+	order := math.Log10(math.Max(math.Abs(float64(score)), 1))
+	sign := 0.0
+	switch {
+	case score > 0:
+		sign = 1
+	case score < 0:
+		sign = -1
+	}
+	return sign*order + float64(p.Created.Unix())/45000
+}
@@ -0,0 +1,254 @@
+package post
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// shardCount is the number of lock stripes the post map is split into.
+const shardCount = 32
+
+// entry holds the current version of a single post. Readers load the pointer
+// without locking; writers of the same post are serialised by mu and publish a
+// fresh Post value instead of modifying the old one. The post's votes are kept
+// here rather than in the published value, so a vote costs the same however
+// many users have voted before.
+type entry struct {
+	mu     sync.Mutex
+	post   atomic.Pointer[Post]
+	voters map[int]int
+}
+
+func (e *entry) load() Post {
+	return *e.post.Load()
+}
+
+type shard struct {
+	mu      sync.RWMutex
+	entries map[int]*entry
+}
+
+// store is a lock-striped map from post ID to entry.
+type store struct {
+	shards [shardCount]shard
+}
+
+func newStore() *store {
+	st := &store{}
+	for i := range st.shards {
+		st.shards[i].entries = make(map[int]*entry)
+	}
+	return st
+}
+
+func (st *store) shard(id int) *shard {
+	return &st.shards[uint(id)%shardCount]
+}
+
+func (st *store) get(id int) (*entry, bool) {
+	sh := st.shard(id)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	e, ok := sh.entries[id]
+	return e, ok
+}
+
+func (st *store) put(id int, e *entry) {
+	sh := st.shard(id)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.entries[id] = e
+}
+
+// removeIf deletes every entry for which drop returns true, holding the
+// entry's own lock while deciding, and returns the removed posts.
+func (st *store) removeIf(drop func(Post) bool) []Post {
+	removed := []Post{}
+	for i := range st.shards {
+		sh := &st.shards[i]
+		sh.mu.Lock()
+		for id, e := range sh.entries {
+			e.mu.Lock()
+			if post := e.load(); drop(post) {
+				delete(sh.entries, id)
+				removed = append(removed, post)
+			}
+			e.mu.Unlock()
+		}
+		sh.mu.Unlock()
+	}
+	return removed
+}
+
+// listing is the set of posts shown in one list, such as a category, kept as
+// an immutable tree of Post values. Readers take the whole listing as it was
+// at one instant by loading its root; writers are serialised by mu and swap in
+// a new root that shares every untouched node with the old one.
+type listing struct {
+	mu   sync.Mutex
+	root atomic.Pointer[tree]
+}
+
+func newListing() *listing {
+	l := &listing{}
+	l.root.Store(&tree{})
+	return l
+}
+
+func (l *listing) snapshot() *tree {
+	return l.root.Load()
+}
+
+// set adds the post to the listing or replaces its previous version. The
+// post is shared with the entry and other listings and must not change.
+func (l *listing) set(post *Post) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.root.Store(l.snapshot().with(post.ID, post))
+}
+
+func (l *listing) remove(id int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.root.Store(l.snapshot().with(id, nil))
+}
+
+// index maps keys such as a category or an author ID to their listings.
+type index[K comparable] struct {
+	listings sync.Map
+}
+
+func (ix *index[K]) listing(key K) *listing {
+	if l, ok := ix.listings.Load(key); ok {
+		return l.(*listing)
+	}
+	l, _ := ix.listings.LoadOrStore(key, newListing())
+	return l.(*listing)
+}
+
+func (ix *index[K]) snapshot(key K) *tree {
+	l, ok := ix.listings.Load(key)
+	if !ok {
+		return &tree{}
+	}
+	return l.(*listing).snapshot()
+}
+
+const (
+	treeBits  = 5
+	treeWidth = 1 << treeBits
+	treeMask  = treeWidth - 1
+)
+
+// tree is a persistent radix tree of posts keyed by ID, 32 ways per level.
+// It is never modified: with returns a new tree that copies only the nodes on
+// the path to the changed post, one small array per level.
+type tree struct {
+	root  *node
+	shift uint
+	size  int
+}
+
+// node is a leaf, holding posts, at shift 0 and an inner node above it.
+type node struct {
+	children [treeWidth]*node
+	posts    [treeWidth]*Post
+}
+
+// with returns a copy of t in which id maps to post, or to nothing when post
+// is nil.
+func (t *tree) with(id int, post *Post) *tree {
+	next := *t
+	if post != nil {
+		if next.root == nil {
+			next.root = &node{}
+		}
+		// Grow until id fits: the old root becomes the first child.
+		for id>>next.shift>>treeBits != 0 {
+			next.root = &node{children: [treeWidth]*node{next.root}}
+			next.shift += treeBits
+		}
+	} else if next.root == nil || id>>next.shift>>treeBits != 0 {
+		return t
+	}
+
+	var added, removed bool
+	next.root = next.root.with(next.shift, id, post, &added, &removed)
+	switch {
+	case added:
+		next.size++
+	case removed:
+		next.size--
+	}
+	if next.root == nil {
+		next.shift = 0
+	}
+	return &next
+}
+
+// with copies n with id set to post below it. A node emptied by a removal
+// comes back nil, so that the trees of removed posts do not linger.
+func (n *node) with(shift uint, id int, post *Post, added, removed *bool) *node {
+	slot := id >> shift & treeMask
+	copied := *n
+
+	if shift == 0 {
+		if post == nil && n.posts[slot] == nil {
+			return n
+		}
+		*added = n.posts[slot] == nil
+		*removed = post == nil
+		copied.posts[slot] = post
+	} else {
+		child := n.children[slot]
+		if child == nil {
+			if post == nil {
+				return n
+			}
+			child = &node{}
+		}
+		copied.children[slot] = child.with(shift-treeBits, id, post, added, removed)
+	}
+
+	if post == nil && copied.empty() {
+		return nil
+	}
+	return &copied
+}
+
+func (n *node) empty() bool {
+	for i := 0; i < treeWidth; i++ {
+		if n.posts[i] != nil || n.children[i] != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// posts returns the posts of the tree in ID order.
+func (t *tree) posts() []Post {
+	posts := make([]Post, 0, t.size)
+	var walk func(n *node, shift uint)
+	walk = func(n *node, shift uint) {
+		if n == nil {
+			return
+		}
+		if shift == 0 {
+			for _, post := range n.posts {
+				if post != nil {
+					posts = append(posts, *post)
+				}
+			}
+			return
+		}
+		for _, child := range n.children {
+			walk(child, shift-treeBits)
+		}
+	}
+	walk(t.root, t.shift)
+	return posts
+}
@@ -0,0 +1,409 @@
+package post
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"redditclone/internal/utils"
+)
+
+// Run with -race: these tests exist to shake out data races in the striped
+// store as much as to check the results.
+
+// checkListing asserts the invariants every listing must keep while writers
+// are busy: no tombstones, posts in ID order and so no duplicates, and vote
+// counters that never drop below zero.
+func checkListing(t *testing.T, name string, posts []Post) {
+	t.Helper()
+	for i, post := range posts {
+		if post.Deleted {
+			t.Errorf("%s: listed deleted post %d", name, post.ID)
+		}
+		if i > 0 && posts[i-1].ID >= post.ID {
+			t.Errorf("%s: listed post %d after post %d", name, post.ID, posts[i-1].ID)
+		}
+		if post.Upvotes < 0 || post.Downvotes < 0 {
+			t.Errorf("%s: post %d has %d/%d votes", name, post.ID, post.Upvotes, post.Downvotes)
+		}
+	}
+}
+
+func TestConcurrentCreateVoteDeleteList(t *testing.T) {
+	const (
+		seeded   = 64
+		creators = 4
+		created  = 100
+		voters   = 8
+		listers  = 4
+	)
+
+	s := newTestService()
+	for i := 0; i < seeded; i++ {
+		if _, err := s.CreatePost(testPost(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var writers, readers sync.WaitGroup
+	var deleted atomic.Int64
+	done := make(chan struct{})
+
+	for g := 0; g < creators; g++ {
+		writers.Add(1)
+		go func(g int) {
+			defer writers.Done()
+			for i := 0; i < created; i++ {
+				post, err := s.CreatePost(testPost(seeded + g*created + i))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if i%2 == 0 {
+					continue
+				}
+				if err := s.DeletePost(post.ID, post.AuthorID, utils.AnyVersion); err != nil {
+					t.Error(err)
+					return
+				}
+				deleted.Add(1)
+			}
+		}(g)
+	}
+
+	// Every voter upvotes all seeded posts and then takes the vote back on
+	// the odd ones, so the final counts are known whatever the interleaving.
+	for g := 0; g < voters; g++ {
+		writers.Add(1)
+		go func(userID int) {
+			defer writers.Done()
+			for id := 1; id <= seeded; id++ {
+				if _, err := s.UpvotePost(id, userID, utils.AnyVersion); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			for id := 1; id <= seeded; id += 2 {
+				if _, err := s.UnvotePost(id, userID, utils.AnyVersion); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(10_000 + g)
+	}
+
+	for g := 0; g < listers; g++ {
+		readers.Add(1)
+		go func(g int) {
+			defer readers.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				all, _ := s.GetAllPosts()
+				checkListing(t, "all", all)
+				byCategory, _ := s.GetPostsByCategory(fmt.Sprintf("category%d", (g+i)%benchCategories))
+				checkListing(t, "category", byCategory)
+				byUser, _ := s.GetPostsByUser((g+i)%benchAuthors + 1)
+				checkListing(t, "user", byUser)
+			}
+		}(g)
+	}
+
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	all, _ := s.GetAllPosts()
+	checkListing(t, "all", all)
+	if want := seeded + creators*created - int(deleted.Load()); len(all) != want {
+		t.Errorf("listed %d posts, want %d", len(all), want)
+	}
+
+	total := 0
+	for c := 0; c < benchCategories; c++ {
+		posts, _ := s.GetPostsByCategory(fmt.Sprintf("category%d", c))
+		total += len(posts)
+	}
+	if total != len(all) {
+		t.Errorf("categories list %d posts, all posts %d", total, len(all))
+	}
+
+	for id := 1; id <= seeded; id++ {
+		post, err := s.GetPostByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := voters
+		if id%2 == 1 {
+			want = 0
+		}
+		if post.Upvotes != want {
+			t.Errorf("post %d has %d upvotes, want %d", id, post.Upvotes, want)
+		}
+	}
+}
+
+// TestListingsAreSnapshots has a writer bump the posts of a category one
+// after another, round after round. A listing read from one snapshot sees
+// every post at most one round apart, with the later posts never ahead of
+// the earlier ones; reading each post on its own, a slow reader would see a
+// late post at a newer round than an early one.
+func TestListingsAreSnapshots(t *testing.T) {
+	const (
+		posts   = 200
+		rounds  = 200
+		readers = 4
+	)
+
+	s := newTestService()
+	for i := 0; i < posts; i++ {
+		post := testPost(i)
+		post.Category = "snapshot"
+		if _, err := s.CreatePost(post); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < readers; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				listed, _ := s.GetPostsByCategory("snapshot")
+				if len(listed) != posts {
+					t.Errorf("listed %d posts, want %d", len(listed), posts)
+					return
+				}
+				first := listed[0].Version
+				for _, post := range listed {
+					if post.Version > first || post.Version < first-1 {
+						t.Errorf("post %d at version %d next to post %d at version %d",
+							post.ID, post.Version, listed[0].ID, first)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	for round := 0; round < rounds; round++ {
+		vote := s.UpvotePost
+		if round%2 == 1 {
+			vote = s.UnvotePost
+		}
+		for id := 1; id <= posts; id++ {
+			if _, err := vote(id, 1, utils.AnyVersion); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestConcurrentVotesOnOnePost(t *testing.T) {
+	const voters = 100
+
+	s := newTestService()
+	post, err := s.CreatePost(testPost(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for userID := 1; userID <= voters; userID++ {
+		wg.Add(1)
+		go func(userID int) {
+			defer wg.Done()
+			vote := s.UpvotePost
+			if userID%4 == 0 {
+				vote = s.DownvotePost
+			}
+			if _, err := vote(post.ID, userID, utils.AnyVersion); err != nil {
+				t.Error(err)
+			}
+		}(userID)
+	}
+	wg.Wait()
+
+	post, err = s.GetPostByID(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Upvotes != voters*3/4 || post.Downvotes != voters/4 {
+		t.Errorf("got %d/%d votes, want %d/%d", post.Upvotes, post.Downvotes, voters*3/4, voters/4)
+	}
+	if post.Version != voters+1 {
+		t.Errorf("got version %d, want %d", post.Version, voters+1)
+	}
+}
+
+func TestVersionConflictUnderContention(t *testing.T) {
+	const voters = 50
+
+	s := newTestService()
+	post, err := s.CreatePost(testPost(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// All voters race with the same expected version; exactly one may win.
+	var wg sync.WaitGroup
+	var won atomic.Int64
+	for userID := 1; userID <= voters; userID++ {
+		wg.Add(1)
+		go func(userID int) {
+			defer wg.Done()
+			if _, err := s.UpvotePost(post.ID, userID, post.Version); err == nil {
+				won.Add(1)
+			}
+		}(userID)
+	}
+	wg.Wait()
+
+	if won.Load() != 1 {
+		t.Errorf("%d votes succeeded against version %d, want 1", won.Load(), post.Version)
+	}
+}
+
+// The parallel benchmarks compare the striped store with mutexRepository,
+// the single-mutex map it replaced, at 10k posts. Every round starts from a
+// fresh store, so that no user votes on the same post twice.
+const parallelPosts = 10_000
+
+func BenchmarkParallelVote(b *testing.B) {
+	b.Run("striped", func(b *testing.B) {
+		s := fillService(b, parallelPosts)
+		var next atomic.Int64
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				n := int(next.Add(1))
+				if _, err := s.UpvotePost(n%parallelPosts+1, n, utils.AnyVersion); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+	b.Run("mutex", func(b *testing.B) {
+		r := fillRepository(parallelPosts)
+		var next atomic.Int64
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				n := int(next.Add(1))
+				r.upvote(n%parallelPosts+1, n)
+			}
+		})
+	})
+}
+
+// BenchmarkParallelMixed approximates front-page traffic: mostly post reads,
+// some category listings and votes, and the odd new or deleted post.
+func BenchmarkParallelMixed(b *testing.B) {
+	b.Run("striped", func(b *testing.B) {
+		s := fillService(b, parallelPosts)
+		var next atomic.Int64
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				n := int(next.Add(1))
+				id := n%parallelPosts + 1
+				switch n % 100 {
+				case 0:
+					post, _ := s.CreatePost(testPost(n))
+					s.DeletePost(post.ID, post.AuthorID, utils.AnyVersion)
+				case 1, 2, 3, 4, 5, 6, 7, 8, 9, 10:
+					s.GetPostsByCategory(fmt.Sprintf("category%d", n%benchCategories))
+				case 11, 12, 13, 14, 15, 16, 17, 18, 19, 20:
+					s.UpvotePost(id, n, utils.AnyVersion)
+				default:
+					s.GetPostByID(id)
+				}
+			}
+		})
+	})
+	b.Run("mutex", func(b *testing.B) {
+		r := fillRepository(parallelPosts)
+		var next atomic.Int64
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				n := int(next.Add(1))
+				id := n%parallelPosts + 1
+				switch n % 100 {
+				case 0:
+					post := r.create(testPost(n))
+					r.delete(post.ID)
+				case 1, 2, 3, 4, 5, 6, 7, 8, 9, 10:
+					r.byCategory(fmt.Sprintf("category%d", n%benchCategories))
+				case 11, 12, 13, 14, 15, 16, 17, 18, 19, 20:
+					r.upvote(id, n)
+				default:
+					r.mu.Lock()
+					_ = r.posts[id]
+					r.mu.Unlock()
+				}
+			}
+		})
+	})
+}
+
+// BenchmarkVoteHotPost votes on a single post that has already gathered
+// hotVoters votes, each vote by a new user.
+const hotVoters = 10_000
+
+func BenchmarkVoteHotPost(b *testing.B) {
+	b.Run("striped", func(b *testing.B) {
+		s := newTestService()
+		post, err := s.CreatePost(testPost(0))
+		if err != nil {
+			b.Fatal(err)
+		}
+		for userID := 1; userID <= hotVoters; userID++ {
+			if _, err := s.UpvotePost(post.ID, userID, utils.AnyVersion); err != nil {
+				b.Fatal(err)
+			}
+		}
+		next := atomic.Int64{}
+		next.Store(hotVoters)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := s.UpvotePost(post.ID, int(next.Add(1)), utils.AnyVersion); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+	b.Run("mutex", func(b *testing.B) {
+		r := newMutexRepository()
+		post := r.create(testPost(0))
+		for userID := 1; userID <= hotVoters; userID++ {
+			r.upvote(post.ID, userID)
+		}
+		next := atomic.Int64{}
+		next.Store(hotVoters)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				r.upvote(post.ID, int(next.Add(1)))
+			}
+		})
+	})
+}
@@ -0,0 +1,51 @@
+package preferences
+
+import (
+	"redditclone/internal/notification"
+	"redditclone/internal/nsfw"
+	"redditclone/internal/post"
+)
+
+// Languages the site can be shown in.
+var Languages = []string{"en", "ru"}
+
+// Email holds the user's email opt-ins; all are off until the user turns
+// them on.
+type Email struct {
+	Digest     bool `json:"digest"`
+	Replies    bool `json:"replies"`
+	Messages   bool `json:"messages"`
+	Newsletter bool `json:"newsletter"`
+}
+
+// Preferences are a user's settings. Content is kept by the nsfw service and
+// is the same resource as /api/me/content.
+type Preferences struct {
+	DefaultSort   string                `json:"default_sort"`
+	Content       nsfw.Settings         `json:"content"`
+	Notifications notification.Settings `json:"notifications"`
+	Email         Email                 `json:"email"`
+	Timezone      string                `json:"timezone"`
+	Language      string                `json:"language"`
+}
+
+// Defaults are the preferences of a user who has not changed any.
+func Defaults() Preferences {
+	return Preferences{
+		DefaultSort:   post.SortNew,
+		Content:       nsfw.DefaultSettings,
+		Notifications: notification.DefaultSettings,
+		Timezone:      "UTC",
+		Language:      "en",
+	}
+}
+
+// FieldError names the preference that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
@@ -0,0 +1,76 @@
+package preferences
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewPreferencesHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting preferences")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	h.writePreferences(w, h.service.GetPreferences(userID))
+}
+
+// UpdatePreferences applies the fields present in the body on top of the
+// current preferences, so clients may send only what changes.
+func (h *Handler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Updating preferences")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	prefs := h.service.GetPreferences(userID)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&prefs); err != nil {
+		http.Error(w, "Invalid preferences: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	prefs, err := h.service.UpdatePreferences(userID, prefs)
+	if err != nil {
+		var fieldErr *FieldError
+		if errors.As(err, &fieldErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(fieldErr)
+			return
+		}
+
+		http.Error(w, "Could not update preferences", http.StatusInternalServerError)
+		return
+	}
+
+	h.writePreferences(w, prefs)
+}
+
+func (h *Handler) writePreferences(w http.ResponseWriter, prefs Preferences) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(prefs); err != nil {
+		http.Error(w, "Failed to encode preferences", http.StatusInternalServerError)
+		return
+	}
+}
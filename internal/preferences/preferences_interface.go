@@ -0,0 +1,14 @@
+package preferences
+
+import "redditclone/internal/notification"
+
+type Service interface {
+	GetPreferences(userID int) Preferences
+	// UpdatePreferences replaces the user's preferences once all of them
+	// are valid; otherwise it returns a *FieldError and changes nothing.
+	UpdatePreferences(userID int, prefs Preferences) (Preferences, error)
+
+	// DefaultSort is a post.SortPreference.
+	DefaultSort(viewerID int) string
+	NotificationSettings(userID int) notification.Settings
+}
@@ -0,0 +1,92 @@
+package preferences
+
+import (
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata"
+
+	"redditclone/internal/notification"
+	"redditclone/internal/nsfw"
+	"redditclone/internal/post"
+)
+
+type preferencesService struct {
+	mu    sync.RWMutex
+	prefs map[int]Preferences
+
+	nsfwService nsfw.Service
+}
+
+// NewPreferencesService stores preferences per user, except for content
+// settings, which it reads from and writes to nsfwService.
+func NewPreferencesService(nsfwService nsfw.Service) Service {
+	return &preferencesService{
+		prefs:       make(map[int]Preferences),
+		nsfwService: nsfwService,
+	}
+}
+
+func (s *preferencesService) GetPreferences(userID int) Preferences {
+	prefs := s.stored(userID)
+	prefs.Content = s.nsfwService.GetSettings(userID)
+	return prefs
+}
+
+func (s *preferencesService) UpdatePreferences(userID int, prefs Preferences) (Preferences, error) {
+	if err := validate(&prefs); err != nil {
+		return Preferences{}, err
+	}
+
+	content, err := s.nsfwService.UpdateSettings(userID, prefs.Content)
+	if err != nil {
+		return Preferences{}, &FieldError{Field: "content", Message: err.Error()}
+	}
+	prefs.Content = content
+
+	s.mu.Lock()
+	s.prefs[userID] = prefs
+	s.mu.Unlock()
+
+	return prefs, nil
+}
+
+func (s *preferencesService) DefaultSort(viewerID int) string {
+	return s.stored(viewerID).DefaultSort
+}
+
+func (s *preferencesService) NotificationSettings(userID int) notification.Settings {
+	return s.stored(userID).Notifications
+}
+
+func (s *preferencesService) stored(userID int) Preferences {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if prefs, ok := s.prefs[userID]; ok && userID != 0 {
+		return prefs
+	}
+	return Defaults()
+}
+
+// validate checks prefs field by field and normalises the timezone and
+// language.
+func validate(prefs *Preferences) error {
+	if !post.ValidSort(prefs.DefaultSort) {
+		return &FieldError{Field: "default_sort", Message: "must be one of hot, new, top or old"}
+	}
+
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil || prefs.Timezone == "" || strings.EqualFold(prefs.Timezone, "Local") {
+		return &FieldError{Field: "timezone", Message: "must be an IANA time zone such as Europe/Moscow"}
+	}
+	prefs.Timezone = loc.String()
+
+	prefs.Language = strings.ToLower(prefs.Language)
+	for _, language := range Languages {
+		if prefs.Language == language {
+			return nil
+		}
+	}
+	return &FieldError{Field: "language", Message: "must be one of " + strings.Join(Languages, ", ")}
+}
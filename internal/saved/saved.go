@@ -0,0 +1,24 @@
+package saved
+
+import (
+	"time"
+
+	"redditclone/internal/comment"
+	"redditclone/internal/post"
+)
+
+const (
+	ItemPost    = "post"
+	ItemComment = "comment"
+)
+
+// Item is a bookmarked post or comment. Post is always filled in when an
+// item is listed, also for comments, so clients can link to the thread.
+type Item struct {
+	Type      string           `json:"type"`
+	PostID    int              `json:"post_id"`
+	CommentID int              `json:"comment_id,omitempty"`
+	SavedAt   time.Time        `json:"saved_at"`
+	Post      *post.Post       `json:"post,omitempty"`
+	Comment   *comment.Comment `json:"comment,omitempty"`
+}
@@ -0,0 +1,146 @@
+package saved
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"redditclone/internal/user"
+	"redditclone/internal/utils"
+)
+
+type Handler struct {
+	service     Service
+	userService user.Service
+	logger      *log.Logger
+}
+
+func NewSavedHandler(service Service, userService user.Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service:     service,
+		userService: userService,
+		logger:      logger,
+	}
+}
+
+func (h *Handler) SavePost(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Saving a post")
+	h.handlePost(w, r, h.service.SavePost)
+}
+
+func (h *Handler) UnsavePost(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Unsaving a post")
+	h.handlePost(w, r, h.service.UnsavePost)
+}
+
+func (h *Handler) SaveComment(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Saving a comment")
+	h.handleComment(w, r, h.service.SaveComment)
+}
+
+func (h *Handler) UnsaveComment(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Unsaving a comment")
+	h.handleComment(w, r, h.service.UnsaveComment)
+}
+
+func (h *Handler) GetSaved(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting saved items")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	owner, err := h.userService.GetUserByUsername(mux.Vars(r)["userLogin"])
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if owner.ID != userID {
+		http.Error(w, "Saved items are private", http.StatusForbidden)
+		return
+	}
+
+	page, err := utils.ParsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, err := h.service.GetSaved(userID, r.URL.Query().Get("category"))
+	if err != nil {
+		http.Error(w, "Could not retrieve saved items", http.StatusInternalServerError)
+		return
+	}
+
+	start, end := page.Bounds(len(items))
+	utils.SetTotal(w, len(items))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(items[start:end]); err != nil {
+		http.Error(w, "Failed to encode saved items", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request, action func(userID, postID int) error) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["postID"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := action(userID, postID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleComment(w http.ResponseWriter, r *http.Request, action func(userID, postID, commentID int) error) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	postID, err := strconv.Atoi(vars["postID"])
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(vars["commentID"])
+	if err != nil {
+		http.Error(w, "Invalid IDs", http.StatusBadRequest)
+		return
+	}
+
+	if err := action(userID, postID, commentID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrAlreadySaved), errors.Is(err, ErrNotSaved):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	}
+}
@@ -0,0 +1,9 @@
+package saved
+
+type Service interface {
+	SavePost(userID, postID int) error
+	UnsavePost(userID, postID int) error
+	SaveComment(userID, postID, commentID int) error
+	UnsaveComment(userID, postID, commentID int) error
+	GetSaved(userID int, category string) ([]Item, error)
+}
@@ -0,0 +1,165 @@
+package saved
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"redditclone/internal/comment"
+	"redditclone/internal/events"
+	"redditclone/internal/post"
+)
+
+var (
+	ErrAlreadySaved = errors.New("already saved")
+	ErrNotSaved     = errors.New("not saved")
+)
+
+type itemKey struct {
+	postID    int
+	commentID int
+}
+
+type savedService struct {
+	mu             sync.Mutex
+	items          map[int][]Item
+	postService    post.Service
+	commentService comment.Service
+}
+
+// NewSavedService stores bookmarks by reference. Items whose post or comment
+// is deleted are hidden from listings while they can still be restored and
+// dropped for good once the content is purged.
+func NewSavedService(postService post.Service, commentService comment.Service, bus *events.Bus) Service {
+	s := &savedService{
+		items:          make(map[int][]Item),
+		postService:    postService,
+		commentService: commentService,
+	}
+
+	bus.Subscribe(events.PostPurged, s.postPurged)
+	bus.Subscribe(events.CommentPurged, s.commentPurged)
+
+	return s
+}
+
+func (s *savedService) SavePost(userID, postID int) error {
+	if _, err := s.postService.GetPostByID(postID); err != nil {
+		return err
+	}
+
+	return s.save(userID, Item{Type: ItemPost, PostID: postID})
+}
+
+func (s *savedService) UnsavePost(userID, postID int) error {
+	return s.unsave(userID, itemKey{postID: postID})
+}
+
+func (s *savedService) SaveComment(userID, postID, commentID int) error {
+	if _, err := s.commentService.GetComment(postID, commentID); err != nil {
+		return err
+	}
+
+	return s.save(userID, Item{Type: ItemComment, PostID: postID, CommentID: commentID})
+}
+
+func (s *savedService) UnsaveComment(userID, postID, commentID int) error {
+	return s.unsave(userID, itemKey{postID: postID, commentID: commentID})
+}
+
+// GetSaved returns the user's bookmarks, most recently saved first, limited
+// to category when it is not empty.
+func (s *savedService) GetSaved(userID int, category string) ([]Item, error) {
+	s.mu.Lock()
+	stored := append([]Item(nil), s.items[userID]...)
+	s.mu.Unlock()
+
+	items := []Item{}
+	for i := len(stored) - 1; i >= 0; i-- {
+		item := stored[i]
+
+		p, err := s.postService.GetPostByID(item.PostID)
+		if err != nil {
+			continue
+		}
+		if category != "" && p.Category != category {
+			continue
+		}
+		item.Post = &p
+
+		if item.Type == ItemComment {
+			c, err := s.commentService.GetComment(item.PostID, item.CommentID)
+			if err != nil {
+				continue
+			}
+			item.Comment = &c
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (s *savedService) save(userID int, item Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := keyOf(item)
+	for _, existing := range s.items[userID] {
+		if keyOf(existing) == key {
+			return ErrAlreadySaved
+		}
+	}
+
+	item.SavedAt = time.Now()
+	s.items[userID] = append(s.items[userID], item)
+	return nil
+}
+
+func (s *savedService) unsave(userID int, key itemKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := s.items[userID]
+	for i, item := range items {
+		if keyOf(item) == key {
+			s.items[userID] = append(items[:i:i], items[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotSaved
+}
+
+func (s *savedService) postPurged(e events.Event) {
+	if p, ok := e.Payload.(post.Post); ok {
+		s.drop(func(item Item) bool { return item.PostID == p.ID })
+	}
+}
+
+func (s *savedService) commentPurged(e events.Event) {
+	if c, ok := e.Payload.(comment.Comment); ok {
+		s.drop(func(item Item) bool {
+			return item.Type == ItemComment && item.CommentID == c.ID
+		})
+	}
+}
+
+func (s *savedService) drop(match func(Item) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for userID, items := range s.items {
+		kept := items[:0]
+		for _, item := range items {
+			if !match(item) {
+				kept = append(kept, item)
+			}
+		}
+		s.items[userID] = kept
+	}
+}
+
+func keyOf(item Item) itemKey {
+	return itemKey{postID: item.PostID, commentID: item.CommentID}
+}
@@ -0,0 +1,18 @@
+package search
+
+import (
+	"redditclone/internal/comment"
+	"redditclone/internal/post"
+)
+
+const (
+	ResultPost    = "post"
+	ResultComment = "comment"
+)
+
+type Result struct {
+	Type    string           `json:"type"`
+	Score   float64          `json:"score"`
+	Post    *post.Post       `json:"post,omitempty"`
+	Comment *comment.Comment `json:"comment,omitempty"`
+}
@@ -0,0 +1,193 @@
+package search
+
+import (
+	"redditclone/internal/comment"
+	"redditclone/internal/post"
+	"redditclone/internal/utils"
+)
+
+// evaluator computes the set of documents matching a query AST. Author and
+// category filters go through the post store's indexes; the remaining
+// filters are checked per document against the post it belongs to, except
+// dates, which use the document's own creation time.
+type evaluator struct {
+	s        *searchService
+	universe map[docKey]bool
+	posts    map[int]*post.Post
+}
+
+func (s *searchService) newEvaluator() *evaluator {
+	return &evaluator{
+		s:        s,
+		universe: s.index.keys(),
+		posts:    make(map[int]*post.Post),
+	}
+}
+
+func (ev *evaluator) eval(n node) map[docKey]bool {
+	switch n := n.(type) {
+	case andNode:
+		left, right := ev.eval(n.left), ev.eval(n.right)
+		for key := range left {
+			if !right[key] {
+				delete(left, key)
+			}
+		}
+		return left
+	case orNode:
+		left := ev.eval(n.left)
+		for key := range ev.eval(n.right) {
+			left[key] = true
+		}
+		return left
+	case notNode:
+		excluded := ev.eval(n.child)
+		result := make(map[docKey]bool)
+		for key := range ev.universe {
+			if !excluded[key] {
+				result[key] = true
+			}
+		}
+		return result
+	case termNode:
+		return ev.s.index.matchAll(n.terms)
+	case filterNode:
+		return ev.filter(n)
+	}
+	return map[docKey]bool{}
+}
+
+func (ev *evaluator) filter(f filterNode) map[docKey]bool {
+	switch f.field {
+	case "author":
+		return ev.byAuthor(f.value)
+	case "category":
+		return ev.byCategory(f.value)
+	}
+
+	result := make(map[docKey]bool)
+	for key := range ev.universe {
+		if ev.matches(key, f) {
+			result[key] = true
+		}
+	}
+	return result
+}
+
+func (ev *evaluator) byAuthor(username string) map[docKey]bool {
+	result := make(map[docKey]bool)
+	u, err := ev.s.userService.GetUserByUsername(username)
+	if err != nil {
+		return result
+	}
+
+	posts, _ := ev.s.postService.GetPostsByUser(u.ID)
+	for _, p := range posts {
+		result[docKey{kind: ResultPost, id: p.ID}] = true
+	}
+
+	ev.s.mu.RLock()
+	defer ev.s.mu.RUnlock()
+	for _, c := range ev.s.comments {
+		if c.AuthorID == u.ID {
+			result[docKey{kind: ResultComment, id: c.ID}] = true
+		}
+	}
+	return result
+}
+
+func (ev *evaluator) byCategory(category string) map[docKey]bool {
+	result := make(map[docKey]bool)
+	posts, _ := ev.s.postService.GetPostsByCategory(category)
+	inCategory := make(map[int]bool, len(posts))
+	for _, p := range posts {
+		inCategory[p.ID] = true
+		result[docKey{kind: ResultPost, id: p.ID}] = true
+	}
+
+	ev.s.mu.RLock()
+	defer ev.s.mu.RUnlock()
+	for _, c := range ev.s.comments {
+		if inCategory[c.PostID] {
+			result[docKey{kind: ResultComment, id: c.ID}] = true
+		}
+	}
+	return result
+}
+
+func (ev *evaluator) matches(key docKey, f filterNode) bool {
+	postID := key.id
+	var c comment.Comment
+	if key.kind == ResultComment {
+		var ok bool
+		if c, ok = ev.s.comment(key.id); !ok {
+			return false
+		}
+		postID = c.PostID
+	}
+
+	p := ev.post(postID)
+	if p == nil {
+		return false
+	}
+
+	created := p.Created
+	if key.kind == ResultComment {
+		created = c.Created
+	}
+
+	switch f.field {
+	case "type":
+		return p.Type() == f.value
+	case "site":
+		return utils.DomainMatches(p.URL, f.value)
+	case "score":
+		return compare(p.Score(), f.op, f.num)
+	case "before":
+		return created.Before(f.date)
+	case "after":
+		return !created.Before(f.date)
+	}
+	return false
+}
+
+func (ev *evaluator) post(id int) *post.Post {
+	if p, ok := ev.posts[id]; ok {
+		return p
+	}
+
+	var result *post.Post
+	if p, err := ev.s.postService.GetPostByID(id); err == nil {
+		result = &p
+	}
+	ev.posts[id] = result
+	return result
+}
+
+func compare(value int, op string, target int) bool {
+	switch op {
+	case ">":
+		return value > target
+	case ">=":
+		return value >= target
+	case "<":
+		return value < target
+	case "<=":
+		return value <= target
+	}
+	return value == target
+}
+
+// positiveTerms collects the terms that count towards ranking, i.e. those not
+// under a NOT.
+func positiveTerms(n node) []string {
+	switch n := n.(type) {
+	case andNode:
+		return append(positiveTerms(n.left), positiveTerms(n.right)...)
+	case orNode:
+		return append(positiveTerms(n.left), positiveTerms(n.right)...)
+	case termNode:
+		return n.terms
+	}
+	return nil
+}
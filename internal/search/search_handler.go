@@ -0,0 +1,67 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultLimit = 25
+	maxLimit     = 100
+)
+
+type Handler struct {
+	service Service
+	logger  *log.Logger
+}
+
+func NewSearchHandler(service Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Searching posts and comments")
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		http.Error(w, "Missing query", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxLimit)
+	}
+
+	viewerID, _ := r.Context().Value("userID").(int)
+	results, err := h.service.Search(viewerID, query, limit)
+	if err != nil {
+		var queryErr *QueryError
+		if errors.As(err, &queryErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(queryErr)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		http.Error(w, "Failed to encode results", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,160 @@
+package search
+
+import (
+	"math"
+	"sync"
+)
+
+// BM25 parameters, the usual defaults.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+type docKey struct {
+	kind string
+	id   int
+}
+
+// field is a piece of document text with the weight its terms count with, so
+// that a match in a title outranks the same match in a body.
+type field struct {
+	text   string
+	weight float64
+}
+
+type document struct {
+	length float64
+	terms  map[string]float64
+}
+
+// invertedIndex maps stemmed terms to the documents containing them and scores
+// matches with BM25.
+type invertedIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[docKey]float64
+	docs     map[docKey]*document
+	totalLen float64
+}
+
+func newInvertedIndex() *invertedIndex {
+	return &invertedIndex{
+		postings: make(map[string]map[docKey]float64),
+		docs:     make(map[docKey]*document),
+	}
+}
+
+// add indexes a document, replacing any earlier version under the same key.
+func (ix *invertedIndex) add(key docKey, fields ...field) {
+	doc := &document{terms: make(map[string]float64)}
+	for _, f := range fields {
+		for _, term := range tokenize(f.text) {
+			doc.terms[term] += f.weight
+			doc.length += f.weight
+		}
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.removeLocked(key)
+	ix.docs[key] = doc
+	ix.totalLen += doc.length
+	for term, tf := range doc.terms {
+		if ix.postings[term] == nil {
+			ix.postings[term] = make(map[docKey]float64)
+		}
+		ix.postings[term][key] = tf
+	}
+}
+
+func (ix *invertedIndex) remove(key docKey) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.removeLocked(key)
+}
+
+func (ix *invertedIndex) removeLocked(key docKey) {
+	doc, ok := ix.docs[key]
+	if !ok {
+		return
+	}
+
+	for term := range doc.terms {
+		delete(ix.postings[term], key)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	ix.totalLen -= doc.length
+	delete(ix.docs, key)
+}
+
+// score returns the BM25 score of every document matching at least one of
+// terms.
+func (ix *invertedIndex) score(terms []string) map[docKey]float64 {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	scores := make(map[docKey]float64)
+	n := float64(len(ix.docs))
+	if n == 0 {
+		return scores
+	}
+	avgLen := ix.totalLen / n
+
+	seen := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := ix.postings[term]
+		df := float64(len(postings))
+		if df == 0 {
+			continue
+		}
+
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for key, tf := range postings {
+			length := ix.docs[key].length
+			norm := tf + bm25K1*(1-bm25B+bm25B*length/avgLen)
+			scores[key] += idf * tf * (bm25K1 + 1) / norm
+		}
+	}
+	return scores
+}
+
+// keys returns every indexed document.
+func (ix *invertedIndex) keys() map[docKey]bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	keys := make(map[docKey]bool, len(ix.docs))
+	for key := range ix.docs {
+		keys[key] = true
+	}
+	return keys
+}
+
+// matchAll returns the documents that contain every one of terms.
+func (ix *invertedIndex) matchAll(terms []string) map[docKey]bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	matched := make(map[docKey]bool)
+	for key := range ix.postings[terms[0]] {
+		matched[key] = true
+	}
+	for _, term := range terms[1:] {
+		postings := ix.postings[term]
+		for key := range matched {
+			if _, ok := postings[key]; !ok {
+				delete(matched, key)
+			}
+		}
+	}
+	return matched
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestScoreRanking(t *testing.T) {
+	ix := newInvertedIndex()
+	post := func(id int, title, body string) {
+		ix.add(docKey{kind: ResultPost, id: id},
+			field{text: title, weight: titleWeight},
+			field{text: body, weight: bodyWeight})
+	}
+	post(1, "Cooking pasta at home", "Boil the water and add salt.")
+	post(2, "Weekend plans", "Going to try a new pasta recipe with garlic.")
+	post(3, "Garlic bread", "Pasta is fine, but garlic bread is better. Garlic everywhere.")
+	post(4, "Weekend hiking trip", "We walked up the hill and back down.")
+	post(5, "Notes", "Nothing to see here at all, just notes about the weather.")
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		// A match in the title outranks the same match in a body, and a
+		// match in a short body outranks one in a long body.
+		{"title beats body", "pasta", []int{1, 2, 3}},
+		{"repeated term", "garlic", []int{3, 2}},
+		// The rarer term weighs more than the common one.
+		{"two terms", "garlic pasta", []int{3, 2, 1}},
+		{"stemmed", "hikes", []int{4}},
+		{"no match", "bicycle", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores := ix.score(tokenize(tt.query))
+			got := make([]int, 0, len(scores))
+			for key := range scores {
+				got = append(got, key.id)
+			}
+			sort.Slice(got, func(i, j int) bool {
+				return scores[docKey{kind: ResultPost, id: got[i]}] > scores[docKey{kind: ResultPost, id: got[j]}]
+			})
+			if len(got) != len(tt.want) {
+				t.Fatalf("ranking = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ranking = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestScoreAfterRemove(t *testing.T) {
+	ix := newInvertedIndex()
+	a := docKey{kind: ResultPost, id: 1}
+	b := docKey{kind: ResultComment, id: 1}
+	ix.add(a, field{text: "golang generics", weight: titleWeight})
+	ix.add(b, field{text: "golang", weight: bodyWeight})
+
+	ix.add(a, field{text: "rust traits", weight: titleWeight})
+	if scores := ix.score(tokenize("golang")); len(scores) != 1 || scores[b] == 0 {
+		t.Errorf("after replacing the post: scores = %v, want only the comment", scores)
+	}
+
+	ix.remove(b)
+	ix.remove(a)
+	if scores := ix.score(tokenize("golang rust")); len(scores) != 0 {
+		t.Errorf("after removing everything: scores = %v", scores)
+	}
+	if ix.totalLen != 0 || len(ix.postings) != 0 {
+		t.Errorf("index keeps totalLen %v and %d postings", ix.totalLen, len(ix.postings))
+	}
+}
@@ -0,0 +1,9 @@
+package search
+
+import "redditclone/internal/post"
+
+type Service interface {
+	AddFilter(filter post.ListFilter)
+	AddCommentFilter(filter post.CommentFilter)
+	Search(viewerID int, query string, limit int) ([]Result, error)
+}
@@ -0,0 +1,326 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const dateLayout = "2006-01-02"
+
+// QueryError describes a malformed query. Position is the index, in
+// characters, of the offending token within the query.
+type QueryError struct {
+	Position int    `json:"position"`
+	Token    string `json:"token"`
+	Message  string `json:"error"`
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s at position %d (%q)", e.Message, e.Position, e.Token)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokFilter
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	field string
+	value string
+	pos   int
+}
+
+var filterFields = map[string]bool{
+	"author":   true,
+	"category": true,
+	"type":     true,
+	"site":     true,
+	"score":    true,
+	"before":   true,
+	"after":    true,
+}
+
+// lex splits a query into tokens. Filters are written field:value, and the
+// value may be quoted. AND, OR and NOT are operators only in upper case.
+func lex(query string) ([]token, error) {
+	runes := []rune(query)
+	tokens := []token{}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case r == '"':
+			text, end, err := readQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokPhrase, text: text, pos: i})
+			i = end
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+				i++
+			}
+			text := string(runes[start:i])
+
+			name, value, isFilter := strings.Cut(text, ":")
+			if isFilter && !strings.HasPrefix(value, "//") {
+				if !filterFields[strings.ToLower(name)] {
+					return nil, &QueryError{Position: start, Token: text, Message: "unknown filter " + name}
+				}
+				if value == "" && i < len(runes) && runes[i] == '"' {
+					quoted, end, err := readQuoted(runes, i)
+					if err != nil {
+						return nil, err
+					}
+					value = quoted
+					i = end
+				}
+				if value == "" {
+					return nil, &QueryError{Position: start, Token: text, Message: "missing value for " + name}
+				}
+				tokens = append(tokens, token{
+					kind:  tokFilter,
+					text:  string(runes[start:i]),
+					field: strings.ToLower(name),
+					value: value,
+					pos:   start,
+				})
+				continue
+			}
+
+			kind := tokWord
+			switch text {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: start})
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(runes)}), nil
+}
+
+func readQuoted(runes []rune, start int) (string, int, error) {
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] == '"' {
+			return string(runes[start+1 : i]), i + 1, nil
+		}
+	}
+	return "", 0, &QueryError{Position: start, Token: string(runes[start:]), Message: "unterminated quote"}
+}
+
+// Query AST. Terms hold stemmed words that must all appear in a document;
+// filters are checked against the post a document belongs to.
+type (
+	node interface{}
+
+	andNode struct{ left, right node }
+	orNode  struct{ left, right node }
+	notNode struct{ child node }
+
+	termNode struct{ terms []string }
+
+	filterNode struct {
+		field string
+		value string
+		op    string
+		num   int
+		date  time.Time
+	}
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseQuery builds the AST for:
+//
+//	query   = or
+//	or      = and { "OR" and }
+//	and     = unary { ["AND"] unary }
+//	unary   = "NOT" unary | primary
+//	primary = "(" or ")" | word | "phrase" | field:value
+func parseQuery(query string) (node, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, ErrEmptyQuery
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, unexpected(tok)
+	}
+	return root, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokWord, tokPhrase, tokFilter, tokNot, tokLParen:
+		default:
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child: child}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, &QueryError{Position: tok.pos, Token: tok.text, Message: "unbalanced parenthesis"}
+		}
+		return inner, nil
+	case tokWord, tokPhrase:
+		terms := tokenize(tok.text)
+		if len(terms) == 0 {
+			return nil, &QueryError{Position: tok.pos, Token: tok.text, Message: "term is too common to search for"}
+		}
+		return termNode{terms: terms}, nil
+	case tokFilter:
+		return parseFilter(tok)
+	default:
+		return nil, unexpected(tok)
+	}
+}
+
+func unexpected(tok token) error {
+	switch tok.kind {
+	case tokEOF:
+		return &QueryError{Position: tok.pos, Message: "unexpected end of query"}
+	case tokRParen:
+		return &QueryError{Position: tok.pos, Token: tok.text, Message: "unbalanced parenthesis"}
+	default:
+		return &QueryError{Position: tok.pos, Token: tok.text, Message: "unexpected " + tok.text}
+	}
+}
+
+func parseFilter(tok token) (node, error) {
+	f := filterNode{field: tok.field, value: tok.value}
+	fail := func(message string) error {
+		return &QueryError{Position: tok.pos, Token: tok.text, Message: message}
+	}
+
+	switch f.field {
+	case "type":
+		f.value = strings.ToLower(f.value)
+		if f.value != "link" && f.value != "text" && f.value != "image" {
+			return nil, fail("type must be link, text or image")
+		}
+	case "site":
+		f.value = strings.TrimPrefix(strings.ToLower(f.value), "www.")
+	case "score":
+		f.op = "="
+		for _, op := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(f.value, op) {
+				f.op = op
+				break
+			}
+		}
+		num, err := strconv.Atoi(strings.TrimPrefix(f.value, f.op))
+		if err != nil {
+			return nil, fail("score must be a number, optionally prefixed with >, >=, < or <=")
+		}
+		f.num = num
+	case "before", "after":
+		date, err := time.Parse(dateLayout, f.value)
+		if err != nil {
+			return nil, fail("date must be in YYYY-MM-DD format")
+		}
+		f.date = date
+	}
+	return f, nil
+}
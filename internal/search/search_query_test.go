@@ -0,0 +1,125 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// show prints an AST in prefix form, with the stemmed terms of each term node
+// in brackets.
+func show(n node) string {
+	switch n := n.(type) {
+	case andNode:
+		return "(AND " + show(n.left) + " " + show(n.right) + ")"
+	case orNode:
+		return "(OR " + show(n.left) + " " + show(n.right) + ")"
+	case notNode:
+		return "(NOT " + show(n.child) + ")"
+	case termNode:
+		return "[" + strings.Join(n.terms, " ") + "]"
+	case filterNode:
+		if n.field == "score" {
+			return fmt.Sprintf("score%s%d", n.op, n.num)
+		}
+		return n.field + ":" + n.value
+	default:
+		return fmt.Sprintf("%#v", n)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"word", "cats", "[cat]"},
+		{"implicit and", "cats dogs", "(AND [cat] [dog])"},
+		{"explicit and", "cats AND dogs", "(AND [cat] [dog])"},
+		{"and binds tighter than or", "cats OR dogs birds", "(OR [cat] (AND [dog] [bird]))"},
+		{"and before or", "cats dogs OR birds", "(OR (AND [cat] [dog]) [bird])"},
+		{"not binds tightest", "NOT cats dogs", "(AND (NOT [cat]) [dog])"},
+		{"not after or", "cats OR NOT dogs", "(OR [cat] (NOT [dog]))"},
+		{"double not", "NOT NOT cats", "(NOT (NOT [cat]))"},
+		{"or is left associative", "a1 OR b2 OR c3", "(OR (OR [a1] [b2]) [c3])"},
+		{"parentheses", "(cats OR dogs) birds", "(AND (OR [cat] [dog]) [bird])"},
+		{"nested parentheses", "NOT ((cats))", "(NOT [cat])"},
+		{"not of a group", "NOT (cats OR dogs)", "(NOT (OR [cat] [dog]))"},
+		{"operators are upper case only", "cats not dogs", "(AND (AND [cat] [not]) [dog])"},
+		{"phrase", `"running dogs"`, "[run dog]"},
+		{"phrase next to words", `cats "running dogs"`, "(AND [cat] [run dog])"},
+		{"phrase drops stop words", `"the cat"`, "[cat]"},
+		{"author filter", "author:alice", "author:alice"},
+		{"category filter", "category:music", "category:music"},
+		{"filter names ignore case", "Author:alice", "author:alice"},
+		{"quoted filter value", `category:"old music"`, "category:old music"},
+		{"quoted filter value after colon", `author:"bob smith" cats`, "(AND author:bob smith [cat])"},
+		{"filters with words", "cats author:alice OR category:music", "(OR (AND [cat] author:alice) category:music)"},
+		{"not filter", "NOT author:alice", "(NOT author:alice)"},
+		{"score filter", "score:>=10", "score>=10"},
+		{"score filter without operator", "score:5", "score=5"},
+		{"type filter is lower cased", "type:LINK", "type:link"},
+		{"site filter drops www", "site:www.Example.com", "site:example.com"},
+		{"url is a word", "https://example.com", "[http exampl com]"},
+		{"cyrillic", "красивые кошки", "(AND [красив] [кошк])"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := parseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseQuery(%q) error = %v", tt.query, err)
+			}
+			if got := show(n); got != tt.want {
+				t.Errorf("parseQuery(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		position int
+		token    string
+	}{
+		{"unterminated quote", `cats "running dogs`, 5, `"running dogs`},
+		{"unterminated filter quote", `author:"bob`, 7, `"bob`},
+		{"unknown filter", "cats colour:red", 5, "colour:red"},
+		{"missing filter value", "cats author:", 5, "author:"},
+		{"unclosed parenthesis", "(cats OR dogs", 0, "("},
+		{"stray closing parenthesis", "cats) dogs", 4, ")"},
+		{"empty parentheses", "cats ()", 6, ")"},
+		{"dangling or", "cats OR", 7, ""},
+		{"leading or", "OR cats", 0, "OR"},
+		{"dangling not", "cats NOT", 8, ""},
+		{"double and", "cats AND AND dogs", 9, "AND"},
+		{"stop word only", "the", 0, "the"},
+		{"empty phrase", `cats ""`, 5, ""},
+		{"bad type", "type:video", 0, "type:video"},
+		{"bad score", "score:>high", 0, "score:>high"},
+		{"bad date", "before:yesterday", 0, "before:yesterday"},
+		{"position counts characters", "кошки OR )", 9, ")"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseQuery(tt.query)
+			var qerr *QueryError
+			if !errors.As(err, &qerr) {
+				t.Fatalf("parseQuery(%q) error = %v, want a QueryError", tt.query, err)
+			}
+			if qerr.Position != tt.position || qerr.Token != tt.token {
+				t.Errorf("parseQuery(%q) error at %d (%q), want %d (%q): %v",
+					tt.query, qerr.Position, qerr.Token, tt.position, tt.token, qerr)
+			}
+		})
+	}
+
+	for _, query := range []string{"", "   "} {
+		if _, err := parseQuery(query); !errors.Is(err, ErrEmptyQuery) {
+			t.Errorf("parseQuery(%q) error = %v, want %v", query, err, ErrEmptyQuery)
+		}
+	}
+}
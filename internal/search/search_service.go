@@ -0,0 +1,212 @@
+package search
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+
+	"redditclone/internal/comment"
+	"redditclone/internal/events"
+	"redditclone/internal/post"
+	"redditclone/internal/user"
+)
+
+var ErrEmptyQuery = errors.New("search query is empty")
+
+// Field weights: a word in a title counts twice as much as one in a body.
+const (
+	titleWeight = 2
+	bodyWeight  = 1
+)
+
+type searchService struct {
+	index          *invertedIndex
+	postService    post.Service
+	userService    user.Service
+	mu             sync.RWMutex
+	comments       map[int]comment.Comment
+	filters        []post.ListFilter
+	commentFilters []post.CommentFilter
+	logger         *log.Logger
+}
+
+// NewSearchService builds a search index that follows the post and comment
+// services through bus events, so new, deleted and restored content becomes
+// searchable or disappears as soon as the change is made.
+func NewSearchService(postService post.Service, userService user.Service, bus *events.Bus, logger *log.Logger) Service {
+	s := &searchService{
+		index:       newInvertedIndex(),
+		postService: postService,
+		userService: userService,
+		comments:    make(map[int]comment.Comment),
+		logger:      logger,
+	}
+
+	bus.Subscribe(events.PostCreated, s.indexPost)
+	bus.Subscribe(events.PostRestored, s.indexPost)
+	bus.Subscribe(events.PostDeleted, s.removePost)
+	bus.Subscribe(events.PostPurged, s.removePost)
+	bus.Subscribe(events.CommentCreated, s.indexComment)
+	bus.Subscribe(events.CommentRestored, s.indexComment)
+	bus.Subscribe(events.CommentDeleted, s.removeComment)
+	bus.Subscribe(events.CommentPurged, s.removeComment)
+
+	return s
+}
+
+// AddFilter registers a step that every post in the results, and the post
+// of every comment, goes through; results whose post it drops are left out.
+// Filters must be added before the service starts handling requests.
+func (s *searchService) AddFilter(filter post.ListFilter) {
+	s.filters = append(s.filters, filter)
+}
+
+// AddCommentFilter registers a step that every comment in the results goes
+// through along with its post; comments it drops are left out.
+func (s *searchService) AddCommentFilter(filter post.CommentFilter) {
+	s.commentFilters = append(s.commentFilters, filter)
+}
+
+// Search evaluates a query written in the search language (see parseQuery)
+// and ranks the matching documents by the BM25 score of their keywords.
+// Malformed queries are reported as *QueryError.
+func (s *searchService) Search(viewerID int, query string, limit int) ([]Result, error) {
+	root, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := s.newEvaluator().eval(root)
+	relevance := s.index.score(positiveTerms(root))
+
+	scores := make(map[docKey]float64, len(matched))
+	for key := range matched {
+		scores[key] = relevance[key]
+	}
+	return s.resolve(viewerID, scores, limit), nil
+}
+
+// resolve turns scored documents into results, best first, dropping anything
+// whose post has been deleted since it was indexed or is filtered out for the
+// viewer.
+func (s *searchService) resolve(viewerID int, scores map[docKey]float64, limit int) []Result {
+	keys := make([]docKey, 0, len(scores))
+	for key := range scores {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if scores[keys[i]] != scores[keys[j]] {
+			return scores[keys[i]] > scores[keys[j]]
+		}
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind == ResultPost
+		}
+		return keys[i].id > keys[j].id
+	})
+
+	results := []Result{}
+	for _, key := range keys {
+		if limit > 0 && len(results) == limit {
+			break
+		}
+
+		switch key.kind {
+		case ResultPost:
+			p, ok := s.visiblePost(viewerID, key.id)
+			if !ok {
+				continue
+			}
+			results = append(results, Result{Type: ResultPost, Score: scores[key], Post: &p})
+		case ResultComment:
+			c, ok := s.comment(key.id)
+			if !ok {
+				continue
+			}
+			p, ok := s.visiblePost(viewerID, c.PostID)
+			if !ok || !s.visibleComment(viewerID, p, c) {
+				continue
+			}
+			results = append(results, Result{Type: ResultComment, Score: scores[key], Comment: &c})
+		}
+	}
+	return results
+}
+
+func (s *searchService) visiblePost(viewerID, postID int) (post.Post, bool) {
+	p, err := s.postService.GetPostByID(postID)
+	if err != nil {
+		return post.Post{}, false
+	}
+
+	posts := []post.Post{p}
+	for _, filter := range s.filters {
+		if posts = filter(viewerID, posts); len(posts) == 0 {
+			return post.Post{}, false
+		}
+	}
+	return posts[0], true
+}
+
+func (s *searchService) visibleComment(viewerID int, p post.Post, c comment.Comment) bool {
+	comments := []comment.Comment{c}
+	for _, filter := range s.commentFilters {
+		if comments = filter(viewerID, p, comments); len(comments) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *searchService) comment(id int) (comment.Comment, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.comments[id]
+	return c, ok
+}
+
+func (s *searchService) indexPost(e events.Event) {
+	p, ok := e.Payload.(post.Post)
+	if !ok {
+		return
+	}
+
+	s.index.add(docKey{kind: ResultPost, id: p.ID},
+		field{text: p.Title, weight: titleWeight},
+		field{text: p.Text, weight: bodyWeight},
+		field{text: p.URL, weight: bodyWeight},
+	)
+}
+
+func (s *searchService) removePost(e events.Event) {
+	if p, ok := e.Payload.(post.Post); ok {
+		s.index.remove(docKey{kind: ResultPost, id: p.ID})
+	}
+}
+
+func (s *searchService) indexComment(e events.Event) {
+	c, ok := e.Payload.(comment.Comment)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	s.comments[c.ID] = c
+	s.mu.Unlock()
+
+	s.index.add(docKey{kind: ResultComment, id: c.ID}, field{text: c.Text, weight: bodyWeight})
+}
+
+func (s *searchService) removeComment(e events.Event) {
+	c, ok := e.Payload.(comment.Comment)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.comments, c.ID)
+	s.mu.Unlock()
+
+	s.index.remove(docKey{kind: ResultComment, id: c.ID})
+}
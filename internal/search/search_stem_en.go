@@ -0,0 +1,222 @@
+package search
+
+import "strings"
+
+// stemEnglish implements the Porter stemming algorithm. Words that are not
+// plain ASCII letters are returned unchanged.
+func stemEnglish(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	w := []byte(word)
+	w = porterStep1ab(w)
+	w = porterStep1c(w)
+	w = porterStep2(w)
+	w = porterStep3(w)
+	w = porterStep4(w)
+	w = porterStep5(w)
+	return string(w)
+}
+
+func isConsonant(w []byte, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	}
+	return true
+}
+
+// measure counts the VC sequences in w, the "m" of the Porter paper.
+func measure(w []byte) int {
+	n, i := 0, 0
+	for i < len(w) && isConsonant(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !isConsonant(w, i) {
+			i++
+		}
+		if i == len(w) {
+			break
+		}
+		for i < len(w) && isConsonant(w, i) {
+			i++
+		}
+		n++
+	}
+	return n
+}
+
+func hasVowel(w []byte) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsDoubleConsonant(w []byte) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC reports whether w ends consonant-vowel-consonant where the last
+// consonant is not w, x or y.
+func endsCVC(w []byte) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-1) || isConsonant(w, n-2) || !isConsonant(w, n-3) {
+		return false
+	}
+	c := w[n-1]
+	return c != 'w' && c != 'x' && c != 'y'
+}
+
+func hasSuffix(w []byte, suffix string) bool {
+	return strings.HasSuffix(string(w), suffix)
+}
+
+// replaceSuffix swaps suffix for repl when the remaining stem has a measure
+// greater than minMeasure. It reports whether w ended with suffix at all.
+func replaceSuffix(w *[]byte, suffix, repl string, minMeasure int) bool {
+	if !hasSuffix(*w, suffix) {
+		return false
+	}
+	stem := (*w)[:len(*w)-len(suffix)]
+	if measure(stem) > minMeasure {
+		*w = append(stem[:len(stem):len(stem)], repl...)
+	}
+	return true
+}
+
+func porterStep1ab(w []byte) []byte {
+	switch {
+	case hasSuffix(w, "sses"):
+		w = w[:len(w)-2]
+	case hasSuffix(w, "ies"):
+		w = w[:len(w)-2]
+	case hasSuffix(w, "ss"):
+	case hasSuffix(w, "s"):
+		w = w[:len(w)-1]
+	}
+
+	if hasSuffix(w, "eed") {
+		if measure(w[:len(w)-3]) > 0 {
+			w = w[:len(w)-1]
+		}
+		return w
+	}
+
+	var stem []byte
+	switch {
+	case hasSuffix(w, "ed") && hasVowel(w[:len(w)-2]):
+		stem = w[:len(w)-2]
+	case hasSuffix(w, "ing") && hasVowel(w[:len(w)-3]):
+		stem = w[:len(w)-3]
+	default:
+		return w
+	}
+
+	switch {
+	case hasSuffix(stem, "at"), hasSuffix(stem, "bl"), hasSuffix(stem, "iz"):
+		return append(stem[:len(stem):len(stem)], 'e')
+	case endsDoubleConsonant(stem):
+		last := stem[len(stem)-1]
+		if last != 'l' && last != 's' && last != 'z' {
+			return stem[:len(stem)-1]
+		}
+	case measure(stem) == 1 && endsCVC(stem):
+		return append(stem[:len(stem):len(stem)], 'e')
+	}
+	return stem
+}
+
+func porterStep1c(w []byte) []byte {
+	if hasSuffix(w, "y") && hasVowel(w[:len(w)-1]) {
+		w = append(w[:len(w)-1:len(w)-1], 'i')
+	}
+	return w
+}
+
+var porterStep2Suffixes = [][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"bli", "ble"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+	{"logi", "log"},
+}
+
+func porterStep2(w []byte) []byte {
+	for _, rule := range porterStep2Suffixes {
+		if replaceSuffix(&w, rule[0], rule[1], 0) {
+			break
+		}
+	}
+	return w
+}
+
+var porterStep3Suffixes = [][2]string{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+func porterStep3(w []byte) []byte {
+	for _, rule := range porterStep3Suffixes {
+		if replaceSuffix(&w, rule[0], rule[1], 0) {
+			break
+		}
+	}
+	return w
+}
+
+var porterStep4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func porterStep4(w []byte) []byte {
+	// Longer suffixes must win over their own endings ("ement" over "ment").
+	best := ""
+	for _, suffix := range porterStep4Suffixes {
+		if hasSuffix(w, suffix) && len(suffix) > len(best) {
+			best = suffix
+		}
+	}
+	if best == "" {
+		return w
+	}
+
+	stem := w[:len(w)-len(best)]
+	if measure(stem) <= 1 {
+		return w
+	}
+	if best == "ion" {
+		if len(stem) == 0 || (stem[len(stem)-1] != 's' && stem[len(stem)-1] != 't') {
+			return w
+		}
+	}
+	return stem
+}
+
+func porterStep5(w []byte) []byte {
+	if hasSuffix(w, "e") {
+		stem := w[:len(w)-1]
+		m := measure(stem)
+		if m > 1 || (m == 1 && !endsCVC(stem)) {
+			w = stem
+		}
+	}
+	if measure(w) > 1 && endsDoubleConsonant(w) && w[len(w)-1] == 'l' {
+		w = w[:len(w)-1]
+	}
+	return w
+}
@@ -0,0 +1,118 @@
+package search
+
+import "strings"
+
+// Endings used by the Snowball Russian stemmer. Groups ending in "AYa" only
+// match when preceded by "а" or "я", which stays on the stem.
+var (
+	ruPerfectiveGerundAYa = []string{"вшись", "вши", "в"}
+	ruPerfectiveGerund    = []string{"ившись", "ывшись", "ивши", "ывши", "ив", "ыв"}
+	ruAdjective           = []string{"ими", "ыми", "его", "ого", "ему", "ому", "ее", "ие", "ые", "ое", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею"}
+	ruParticipleAYa       = []string{"ем", "нн", "вш", "ющ", "щ"}
+	ruParticiple          = []string{"ивш", "ывш", "ующ"}
+	ruReflexive           = []string{"ся", "сь"}
+	ruVerbAYa             = []string{"ете", "йте", "ешь", "нно", "ла", "на", "ли", "ем", "ло", "но", "ет", "ют", "ны", "ть", "й", "л", "н"}
+	ruVerb                = []string{"ейте", "уйте", "ила", "ыла", "ена", "ите", "или", "ыли", "ило", "ыло", "ено", "ует", "уют", "ены", "ить", "ыть", "ишь", "ей", "уй", "ил", "ыл", "им", "ым", "ен", "ят", "ит", "ыт", "ую", "ю"}
+	ruNoun                = []string{"иями", "ями", "ами", "ией", "иям", "ием", "иях", "ев", "ов", "ие", "ье", "еи", "ии", "ей", "ой", "ий", "ям", "ем", "ам", "ом", "ах", "ях", "ию", "ью", "ия", "ья", "а", "е", "и", "й", "о", "у", "ы", "ь", "ю", "я"}
+	ruSuperlative         = []string{"ейше", "ейш"}
+	ruDerivational        = []string{"ость", "ост"}
+)
+
+func isRussianVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+// stemRussian implements the Snowball Russian stemming algorithm.
+func stemRussian(word string) string {
+	w := []rune(strings.ReplaceAll(word, "ё", "е"))
+
+	// RV starts after the first vowel; R2 is the standard Snowball region
+	// used for derivational endings.
+	rv := len(w)
+	for i, r := range w {
+		if isRussianVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	r2 := region(w, region(w, 0))
+
+	// Step 1.
+	if stem, ok := trimEnding(w, rv, ruPerfectiveGerundAYa, true); ok {
+		w = stem
+	} else if stem, ok := trimEnding(w, rv, ruPerfectiveGerund, false); ok {
+		w = stem
+	} else {
+		if stem, ok := trimEnding(w, rv, ruReflexive, false); ok {
+			w = stem
+		}
+
+		if stem, ok := trimEnding(w, rv, ruAdjective, false); ok {
+			w = stem
+			if stem, ok := trimEnding(w, rv, ruParticipleAYa, true); ok {
+				w = stem
+			} else if stem, ok := trimEnding(w, rv, ruParticiple, false); ok {
+				w = stem
+			}
+		} else if stem, ok := trimEnding(w, rv, ruVerbAYa, true); ok {
+			w = stem
+		} else if stem, ok := trimEnding(w, rv, ruVerb, false); ok {
+			w = stem
+		} else if stem, ok := trimEnding(w, rv, ruNoun, false); ok {
+			w = stem
+		}
+	}
+
+	// Step 2.
+	if stem, ok := trimEnding(w, rv, []string{"и"}, false); ok {
+		w = stem
+	}
+
+	// Step 3.
+	if stem, ok := trimEnding(w, r2, ruDerivational, false); ok {
+		w = stem
+	}
+
+	// Step 4.
+	if stem, ok := trimEnding(w, rv, ruSuperlative, false); ok {
+		w = stem
+	}
+	if stem, ok := trimEnding(w, rv, []string{"нн"}, false); ok {
+		w = append(stem, 'н')
+	} else if stem, ok := trimEnding(w, rv, []string{"ь"}, false); ok {
+		w = stem
+	}
+
+	return string(w)
+}
+
+// region returns the index after the first non-vowel that follows a vowel at
+// or after start, the R1/R2 definition of the Snowball algorithms.
+func region(w []rune, start int) int {
+	for i := start + 1; i < len(w); i++ {
+		if !isRussianVowel(w[i]) && isRussianVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// trimEnding removes the first of endings (ordered longest first) that lies
+// entirely at or after limit. With afterAYa the ending must also be preceded,
+// inside the same region, by "а" or "я".
+func trimEnding(w []rune, limit int, endings []string, afterAYa bool) ([]rune, bool) {
+	for _, ending := range endings {
+		e := []rune(ending)
+		start := len(w) - len(e)
+		if start < limit || string(w[start:]) != ending {
+			continue
+		}
+		if afterAYa {
+			if start-1 < limit || (w[start-1] != 'а' && w[start-1] != 'я') {
+				continue
+			}
+		}
+		return w[:start:start], true
+	}
+	return w, false
+}
@@ -0,0 +1,149 @@
+package search
+
+import "testing"
+
+func TestStemEnglish(t *testing.T) {
+	// Samples from the Porter paper and the published Porter vocabulary.
+	tests := map[string]string{
+		"caresses":        "caress",
+		"ponies":          "poni",
+		"ties":            "ti",
+		"caress":          "caress",
+		"cats":            "cat",
+		"feed":            "feed",
+		"agreed":          "agre",
+		"plastered":       "plaster",
+		"bled":            "bled",
+		"motoring":        "motor",
+		"sing":            "sing",
+		"conflated":       "conflat",
+		"troubled":        "troubl",
+		"sized":           "size",
+		"hopping":         "hop",
+		"tanned":          "tan",
+		"falling":         "fall",
+		"hissing":         "hiss",
+		"fizzed":          "fizz",
+		"failing":         "fail",
+		"filing":          "file",
+		"happy":           "happi",
+		"sky":             "sky",
+		"relational":      "relat",
+		"conditional":     "condit",
+		"rational":        "ration",
+		"valenci":         "valenc",
+		"hesitanci":       "hesit",
+		"digitizer":       "digit",
+		"conformabli":     "conform",
+		"radicalli":       "radic",
+		"differentli":     "differ",
+		"vileli":          "vile",
+		"analogousli":     "analog",
+		"vietnamization":  "vietnam",
+		"predication":     "predic",
+		"operator":        "oper",
+		"feudalism":       "feudal",
+		"decisiveness":    "decis",
+		"hopefulness":     "hope",
+		"callousness":     "callous",
+		"formaliti":       "formal",
+		"sensitiviti":     "sensit",
+		"sensibiliti":     "sensibl",
+		"triplicate":      "triplic",
+		"formative":       "form",
+		"formalize":       "formal",
+		"electriciti":     "electr",
+		"electrical":      "electr",
+		"hopeful":         "hope",
+		"goodness":        "good",
+		"revival":         "reviv",
+		"allowance":       "allow",
+		"inference":       "infer",
+		"airliner":        "airlin",
+		"gyroscopic":      "gyroscop",
+		"adjustable":      "adjust",
+		"defensible":      "defens",
+		"irritant":        "irrit",
+		"replacement":     "replac",
+		"adjustment":      "adjust",
+		"dependent":       "depend",
+		"adoption":        "adopt",
+		"homologou":       "homolog",
+		"communism":       "commun",
+		"activate":        "activ",
+		"angulariti":      "angular",
+		"homologous":      "homolog",
+		"effective":       "effect",
+		"bowdlerize":      "bowdler",
+		"probate":         "probat",
+		"rate":            "rate",
+		"cease":           "ceas",
+		"controll":        "control",
+		"roll":            "roll",
+		"generalizations": "gener",
+		"oscillators":     "oscil",
+		// Short and non-ASCII words are left alone.
+		"go":   "go",
+		"café": "café",
+		"mp3":  "mp3",
+		"a":    "a",
+	}
+	for word, want := range tests {
+		if got := stemEnglish(word); got != want {
+			t.Errorf("stemEnglish(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestStemRussian(t *testing.T) {
+	// Samples from the published Snowball Russian vocabulary.
+	tests := map[string]string{
+		"в":                "в",
+		"вагнера":          "вагнер",
+		"важная":           "важн",
+		"важнее":           "важн",
+		"важнейшие":        "важн",
+		"важно":            "важн",
+		"вазы":             "ваз",
+		"вал":              "вал",
+		"валялась":         "валя",
+		"валялся":          "валя",
+		"ваниль":           "ванил",
+		"варвара":          "варвар",
+		"варенье":          "варен",
+		"вареньем":         "варен",
+		"василий":          "васил",
+		"вашего":           "ваш",
+		"вбежал":           "вбежа",
+		"вбежала":          "вбежа",
+		"вверх":            "вверх",
+		"вдруг":            "вдруг",
+		"длинный":          "длин",
+		"красивая":         "красив",
+		"книги":            "книг",
+		"организация":      "организац",
+		"деятельность":     "деятельн",
+		"программирование": "программирован",
+		"высокий":          "высок",
+		"поисковых":        "поисков",
+		"ёлка":             "елк",
+	}
+	for word, want := range tests {
+		if got := stemRussian(word); got != want {
+			t.Errorf("stemRussian(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("The Running dogs и Красивые кошки, v2!")
+	want := []string{"run", "dog", "красив", "кошк", "v2"}
+	if len(got) != len(want) {
+		t.Fatalf("tokenize() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("tokenize() = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are frequent words that carry no meaning for ranking.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"to": true, "was": true, "with": true,
+	"и": true, "в": true, "во": true, "не": true, "на": true, "с": true,
+	"со": true, "что": true, "а": true, "но": true, "к": true, "у": true,
+	"по": true, "из": true, "за": true, "от": true, "о": true, "об": true,
+}
+
+// tokenize splits text into lowercase words and stems each one with the
+// stemmer matching its script.
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if stopWords[word] {
+			continue
+		}
+		terms = append(terms, stem(word))
+	}
+	return terms
+}
+
+func stem(word string) string {
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return stemRussian(word)
+		}
+	}
+	return stemEnglish(word)
+}
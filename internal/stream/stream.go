@@ -0,0 +1,146 @@
+package stream
+
+import (
+	"time"
+
+	"redditclone/internal/comment"
+	"redditclone/internal/events"
+	"redditclone/internal/message"
+	"redditclone/internal/notification"
+	"redditclone/internal/post"
+)
+
+const (
+	UpdatePost         = "post"
+	UpdateComment      = "comment"
+	UpdateScore        = "score"
+	UpdateNotification = "notification"
+	UpdateLive         = "live"
+	UpdateMessage      = "message"
+)
+
+// Streamed lists the bus events that are turned into updates.
+var Streamed = []string{
+	events.PostCreated,
+	events.PostVoted,
+	events.CommentCreated,
+	events.CommentVoted,
+	events.NotificationCreated,
+	events.LiveThreadUpdated,
+	events.MessageSent,
+}
+
+// Update is a single live change sent to clients. Category, PostID and
+// UserID are used to route it and are not part of the payload.
+type Update struct {
+	Type     string      `json:"type"`
+	Category string      `json:"-"`
+	PostID   int         `json:"-"`
+	UserID   int         `json:"-"`
+	Data     interface{} `json:"data"`
+
+	// post is the post a new-post or post-score update is about, for Filters.
+	post *post.Post
+}
+
+// Score is the payload of a score update. CommentID is set when a comment
+// was voted on.
+type Score struct {
+	PostID    int `json:"post_id"`
+	CommentID int `json:"comment_id,omitempty"`
+	Score     int `json:"score"`
+	Upvotes   int `json:"upvotes"`
+	Downvotes int `json:"downvotes"`
+}
+
+// LiveUpdate is a message the post's author adds to a live thread. It is
+// published as LiveThreadUpdated and not stored.
+type LiveUpdate struct {
+	PostID   int       `json:"post_id"`
+	AuthorID int       `json:"author_id"`
+	Text     string    `json:"text"`
+	Created  time.Time `json:"created"`
+}
+
+// FromEvent converts a bus event into an update. It reports false for events
+// that are not streamed.
+func FromEvent(e events.Event) (Update, bool) {
+	switch payload := e.Payload.(type) {
+	case post.Post:
+		if e.Type != events.PostCreated {
+			return Update{}, false
+		}
+		return Update{Type: UpdatePost, Category: payload.Category, PostID: payload.ID, Data: payload, post: &payload}, true
+	case post.VoteEvent:
+		p := payload.Post
+		return Update{Type: UpdateScore, Category: p.Category, PostID: p.ID, Data: Score{
+			PostID:    p.ID,
+			Score:     p.Score(),
+			Upvotes:   p.Upvotes,
+			Downvotes: p.Downvotes,
+		}, post: &p}, true
+	case comment.Comment:
+		if e.Type != events.CommentCreated {
+			return Update{}, false
+		}
+		return Update{Type: UpdateComment, PostID: payload.PostID, Data: payload}, true
+	case comment.VoteEvent:
+		c := payload.Comment
+		return Update{Type: UpdateScore, PostID: c.PostID, Data: Score{
+			PostID:    c.PostID,
+			CommentID: c.ID,
+			Score:     c.Score(),
+			Upvotes:   c.Upvotes,
+			Downvotes: c.Downvotes,
+		}}, true
+	case notification.Notification:
+		return Update{Type: UpdateNotification, UserID: payload.UserID, Data: payload}, true
+	case message.Message:
+		return Update{Type: UpdateMessage, UserID: payload.RecipientID, Data: payload}, true
+	case LiveUpdate:
+		return Update{Type: UpdateLive, PostID: payload.PostID, Data: payload}, true
+	}
+	return Update{}, false
+}
+
+// Topics selects the updates a client receives. New posts and post scores
+// follow Categories, or every category when none is given; comments, comment
+// scores and live thread updates follow Posts; notifications and direct
+// messages go to UserID only.
+type Topics struct {
+	Categories map[string]bool
+	Posts      map[int]bool
+	UserID     int
+}
+
+func NewTopics(userID int) Topics {
+	return Topics{
+		Categories: make(map[string]bool),
+		Posts:      make(map[int]bool),
+		UserID:     userID,
+	}
+}
+
+func (t Topics) Match(u Update) bool {
+	switch u.Type {
+	case UpdateNotification, UpdateMessage:
+		return t.UserID != 0 && u.UserID == t.UserID
+	case UpdateComment, UpdateLive:
+		return t.Posts[u.PostID]
+	case UpdateScore:
+		if u.Category == "" {
+			return t.Posts[u.PostID]
+		}
+		return t.Posts[u.PostID] || t.followsCategory(u.Category)
+	case UpdatePost:
+		return t.followsCategory(u.Category)
+	}
+	return false
+}
+
+func (t Topics) followsCategory(category string) bool {
+	if len(t.Categories) == 0 {
+		return len(t.Posts) == 0
+	}
+	return t.Categories[category]
+}
@@ -0,0 +1,90 @@
+package stream
+
+import (
+	"redditclone/internal/comment"
+	"redditclone/internal/post"
+)
+
+// Filters are the per-viewer checks an update goes through before it is
+// sent, so that live clients see no more than the post handler shows them.
+type Filters struct {
+	postService post.Service
+	list        []post.ListFilter
+	view        []post.ListFilter
+	comments    []post.CommentFilter
+}
+
+func NewFilters(postService post.Service) *Filters {
+	return &Filters{postService: postService}
+}
+
+// AddListFilter registers a filter run, like a listing filter, over the post
+// of new-post and post-score updates. Filters must be added before clients
+// connect.
+func (f *Filters) AddListFilter(filter post.ListFilter) {
+	f.list = append(f.list, filter)
+}
+
+// AddViewFilter registers a filter run over the post of every update about
+// a post, live thread updates included, as on the post page.
+func (f *Filters) AddViewFilter(filter post.ListFilter) {
+	f.view = append(f.view, filter)
+}
+
+// AddCommentFilter registers a filter run over the comment of new-comment
+// updates, together with the comment's post.
+func (f *Filters) AddCommentFilter(filter post.CommentFilter) {
+	f.comments = append(f.comments, filter)
+}
+
+// Apply reports whether viewerID, 0 for anonymous clients, may receive u,
+// and returns u with the post it carries adjusted by the filters. Updates
+// whose post the filters drop are not sent.
+func (f *Filters) Apply(viewerID int, u Update) (Update, bool) {
+	switch data := u.Data.(type) {
+	case comment.Comment:
+		p, err := f.postService.GetPostByID(data.PostID)
+		if err != nil {
+			return Update{}, false
+		}
+		comments := []comment.Comment{data}
+		for _, filter := range f.comments {
+			if comments = filter(viewerID, p, comments); len(comments) == 0 {
+				return Update{}, false
+			}
+		}
+		return u, true
+	case LiveUpdate:
+		p, err := f.postService.GetPostByID(data.PostID)
+		if err != nil {
+			return Update{}, false
+		}
+		_, ok := run(f.view, viewerID, p)
+		return u, ok
+	}
+
+	if u.post == nil {
+		return u, true
+	}
+	p, ok := run(f.list, viewerID, *u.post)
+	if ok {
+		p, ok = run(f.view, viewerID, p)
+	}
+	if !ok {
+		return Update{}, false
+	}
+	if u.Type == UpdatePost {
+		u.Data = p
+	}
+	return u, true
+}
+
+func run(filters []post.ListFilter, viewerID int, p post.Post) (post.Post, bool) {
+	posts := []post.Post{p}
+	for _, filter := range filters {
+		if posts = filter(viewerID, posts); len(posts) == 0 {
+			return post.Post{}, false
+		}
+	}
+	return posts[0], true
+}
@@ -0,0 +1,157 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"redditclone/internal/events"
+	"redditclone/internal/post"
+	"redditclone/internal/utils"
+)
+
+const (
+	// bufferSize is how many updates may wait for a slow client before
+	// newer ones are dropped.
+	bufferSize = 64
+	// writeTimeout bounds a single write so a stalled connection is closed
+	// instead of holding its subscription forever.
+	writeTimeout      = 10 * time.Second
+	heartbeatInterval = 30 * time.Second
+)
+
+type Handler struct {
+	bus     *events.Bus
+	filters *Filters
+	logger  *log.Logger
+}
+
+func NewStreamHandler(bus *events.Bus, postService post.Service, logger *log.Logger) *Handler {
+	return &Handler{
+		bus:     bus,
+		filters: NewFilters(postService),
+		logger:  logger,
+	}
+}
+
+// AddListFilter hides new posts and their scores from the viewers the
+// listings hide them from, see Filters.
+func (h *Handler) AddListFilter(filter post.ListFilter) {
+	h.filters.AddListFilter(filter)
+}
+
+// AddViewFilter hides or adjusts every update about a post, as the post
+// page does.
+func (h *Handler) AddViewFilter(filter post.ListFilter) {
+	h.filters.AddViewFilter(filter)
+}
+
+// AddCommentFilter hides new comments as the post page does.
+func (h *Handler) AddCommentFilter(filter post.CommentFilter) {
+	h.filters.AddCommentFilter(filter)
+}
+
+// Stream serves live updates as Server-Sent Events. Browsers cannot set
+// headers on an EventSource, so the JWT may also be passed as ?token=.
+// Updates dropped because the client fell behind are reported in a "lagged"
+// event so it can refetch.
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Opening event stream")
+
+	userID, err := streamUser(r)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	topics := NewTopics(userID)
+	query := r.URL.Query()
+	for _, category := range query["category"] {
+		topics.Categories[category] = true
+	}
+	for _, value := range query["post"] {
+		postID, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+		topics.Posts[postID] = true
+	}
+
+	rc := http.NewResponseController(w)
+	sub := h.bus.SubscribeAsync(bufferSize, Streamed...)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	id := 0
+	for {
+		var frame string
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			frame = ": ping\n\n"
+		case e := <-sub.C:
+			if dropped := sub.Dropped(); dropped > 0 {
+				id++
+				frame = sseFrame(id, "lagged", map[string]int64{"dropped": dropped})
+			}
+
+			u, ok := FromEvent(e)
+			if !ok || !topics.Match(u) {
+				break
+			}
+			if u, ok = h.filters.Apply(userID, u); !ok {
+				break
+			}
+			id++
+			frame += sseFrame(id, u.Type, u.Data)
+		}
+		if frame == "" {
+			continue
+		}
+
+		rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := fmt.Fprint(w, frame); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func sseFrame(id int, event string, data interface{}) string {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", id, event, payload)
+}
+
+// streamUser returns the user named by the Authorization header or the token
+// parameter, or 0 for anonymous clients.
+func streamUser(r *http.Request) (int, error) {
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = header[len("Bearer "):]
+	}
+	if token == "" {
+		return 0, nil
+	}
+	return utils.ParseJWT(token)
+}
@@ -2,20 +2,26 @@ package user
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
 
 	"redditclone/internal/utils"
 )
 
 type Handler struct {
 	service Service
+	karma   KarmaSource
 	logger  *log.Logger
 }
 
-func NewUserHandler(service Service, logger *log.Logger) *Handler {
+func NewUserHandler(service Service, karma KarmaSource, logger *log.Logger) *Handler {
 	return &Handler{
 		service: service,
+		karma:   karma,
 		logger:  logger,
 	}
 }
@@ -84,3 +90,66 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+type UpdateProfileRequest struct {
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	Avatar      string `json:"avatar"`
+}
+
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Getting user profile")
+
+	user, err := h.service.GetUserByUsername(mux.Vars(r)["userLogin"])
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	h.writeProfile(w, user)
+}
+
+func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Updating user profile")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.UpdateProfile(userID, req.DisplayName, req.Bio, req.Avatar)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrDisplayNameTooLong), errors.Is(err, ErrBioTooLong), errors.Is(err, ErrInvalidAvatar):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "User not found", http.StatusNotFound)
+		}
+		return
+	}
+
+	h.writeProfile(w, user)
+}
+
+func (h *Handler) writeProfile(w http.ResponseWriter, user User) {
+	postKarma, commentKarma := h.karma.Karma(user.ID)
+	profile := Profile{
+		User:           user,
+		AccountAgeDays: int(time.Since(user.Created) / (24 * time.Hour)),
+		PostKarma:      postKarma,
+		CommentKarma:   commentKarma,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(profile); err != nil {
+		http.Error(w, "Failed to encode profile", http.StatusInternalServerError)
+		return
+	}
+}
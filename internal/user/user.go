@@ -1,7 +1,27 @@
 package user
 
+import "time"
+
 type User struct {
-	ID       int    `json:"id"`
-	Username string `json:"username"`
-	Password string `json:"-"`
+	ID          int       `json:"id"`
+	Username    string    `json:"username"`
+	Password    string    `json:"-"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Bio         string    `json:"bio,omitempty"`
+	Avatar      string    `json:"avatar,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+// Profile is the public view of a user, with karma earned from votes on
+// their posts and comments.
+type Profile struct {
+	User
+	AccountAgeDays int `json:"account_age_days"`
+	PostKarma      int `json:"post_karma"`
+	CommentKarma   int `json:"comment_karma"`
+}
+
+// KarmaSource reports the karma a user has earned.
+type KarmaSource interface {
+	Karma(userID int) (postKarma, commentKarma int)
 }
@@ -0,0 +1,59 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+
+	"redditclone/internal/events"
+)
+
+const benchUsers = 100_000
+
+// scanUsername looks a user up the way GetUserByUsername did before the
+// username index: by scanning every user under the lock.
+func (s *userService) scanUsername(username string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return User{}, errors.New("user not found")
+}
+
+// fillUsers adds users directly, as hashing 100k passwords would take far
+// longer than the lookups being measured.
+func fillUsers(n int) *userService {
+	s := NewUserService(log.New(io.Discard, "", 0), events.NewBus()).(*userService)
+	for i := 0; i < n; i++ {
+		user := User{ID: s.nextID, Username: fmt.Sprintf("user%d", i)}
+		s.nextID++
+		s.users[user.ID] = user
+		s.byUsername[user.Username] = user.ID
+	}
+	return s
+}
+
+func BenchmarkGetUserByUsername(b *testing.B) {
+	s := fillUsers(benchUsers)
+
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := s.GetUserByUsername(fmt.Sprintf("user%d", i%benchUsers)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := s.scanUsername(fmt.Sprintf("user%d", i%benchUsers)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
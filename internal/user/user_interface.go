@@ -5,4 +5,5 @@ type Service interface {
 	Login(username, password string) (User, error)
 	GetUserByID(id int) (User, error)
 	GetUserByUsername(username string) (User, error)
+	UpdateProfile(userID int, displayName, bio, avatar string) (User, error)
 }
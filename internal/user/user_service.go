@@ -3,67 +3,79 @@ package user
 import (
 	"errors"
 	"log"
+	"net/url"
+	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/bcrypt"
+
+	"redditclone/internal/events"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 500
+)
+
+var (
+	ErrDisplayNameTooLong = errors.New("display name must be at most 50 characters")
+	ErrBioTooLong         = errors.New("bio must be at most 500 characters")
+	ErrInvalidAvatar      = errors.New("avatar must be an http or https URL")
 )
 
 type userService struct {
-	mu     sync.Mutex
-	users  map[int]User
-	nextID int
-	logger *log.Logger
+	mu         sync.RWMutex
+	users      map[int]User
+	byUsername map[string]int
+	nextID     int
+	bus        *events.Bus
+	logger     *log.Logger
 }
 
-func NewUserService(logger *log.Logger) Service {
+func NewUserService(logger *log.Logger, bus *events.Bus) Service {
 	return &userService{
-		users:  make(map[int]User),
-		nextID: 1,
-		logger: logger,
+		users:      make(map[int]User),
+		byUsername: make(map[string]int),
+		nextID:     1,
+		bus:        bus,
+		logger:     logger,
 	}
 }
 
 func (s *userService) Register(username, password string) (User, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	for _, u := range s.users {
-		if u.Username == username {
-			return User{}, errors.New("username already exists")
-		}
-	}
-
+	// Hashing is slow on purpose, so it is done before taking the lock.
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return User{}, err
 	}
 
+	s.mu.Lock()
+	if _, exists := s.byUsername[username]; exists {
+		s.mu.Unlock()
+		return User{}, errors.New("username already exists")
+	}
+
 	user := User{
 		ID:       s.nextID,
 		Username: username,
 		Password: string(hashedPassword),
+		Created:  time.Now(),
 	}
 	s.nextID++
 	s.users[user.ID] = user
+	s.byUsername[username] = user.ID
+	s.mu.Unlock()
 
+	s.bus.Publish(events.UserRegistered, user)
 	return user, nil
 }
 
 func (s *userService) Login(username, password string) (User, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	var user User
-	found := false
-	for _, u := range s.users {
-		if u.Username == username {
-			user = u
-			found = true
-			break
-		}
-	}
-	if !found {
-		return User{}, errors.New("user not found")
+	user, err := s.GetUserByUsername(username)
+	if err != nil {
+		return User{}, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
@@ -74,8 +86,8 @@ func (s *userService) Login(username, password string) (User, error) {
 }
 
 func (s *userService) GetUserByID(id int) (User, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	user, exists := s.users[id]
 	if !exists {
@@ -85,13 +97,46 @@ func (s *userService) GetUserByID(id int) (User, error) {
 }
 
 func (s *userService) GetUserByUsername(username string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, exists := s.byUsername[username]
+	if !exists {
+		return User{}, errors.New("user not found")
+	}
+	return s.users[id], nil
+}
+
+func (s *userService) UpdateProfile(userID int, displayName, bio, avatar string) (User, error) {
+	displayName = strings.TrimSpace(displayName)
+	bio = strings.TrimSpace(bio)
+	avatar = strings.TrimSpace(avatar)
+
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		return User{}, ErrDisplayNameTooLong
+	}
+	if utf8.RuneCountInString(bio) > maxBioLength {
+		return User{}, ErrBioTooLong
+	}
+	if avatar != "" {
+		u, err := url.Parse(avatar)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return User{}, ErrInvalidAvatar
+		}
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, user := range s.users {
-		if user.Username == username {
-			return user, nil
-		}
+	user, exists := s.users[userID]
+	if !exists {
+		return User{}, errors.New("user not found")
 	}
-	return User{}, errors.New("user not found")
+
+	user.DisplayName = displayName
+	user.Bio = bio
+	user.Avatar = avatar
+	s.users[userID] = user
+
+	return user, nil
 }
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AnyVersion is passed to compare-and-swap service methods when the client
+// sent "If-Match: *" and accepts whatever version is current.
+const AnyVersion = -1
+
+var (
+	ErrPreconditionRequired = errors.New("If-Match header is required")
+	ErrInvalidIfMatch       = errors.New("invalid If-Match header")
+)
+
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// IfMatchVersion extracts the expected resource version from the If-Match
+// header. Only a single strong or weak entity tag produced by ETag, or "*",
+// is accepted.
+func IfMatchVersion(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, ErrPreconditionRequired
+	}
+
+	if header == "*" {
+		return AnyVersion, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, ErrInvalidIfMatch
+	}
+
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version < 0 {
+		return 0, ErrInvalidIfMatch
+	}
+
+	return version, nil
+}
+
+// WritePreconditionError maps an IfMatchVersion error to its HTTP status.
+func WritePreconditionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrPreconditionRequired) {
+		http.Error(w, err.Error(), http.StatusPreconditionRequired)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
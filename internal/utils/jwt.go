@@ -2,21 +2,30 @@ package utils
 
 import (
 	"errors"
-	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
-var jwtKey []byte
+var ErrNoJWTKey = errors.New("JWT_SECRET_KEY is not set")
 
-func init() {
-	jwtKeyStr := os.Getenv("JWT_SECRET_KEY")
-	if jwtKeyStr == "" {
-		log.Fatal("JWT_SECRET_KEY не установлен")
+var (
+	jwtKeyOnce sync.Once
+	jwtKey     []byte
+)
+
+// signingKey reads JWT_SECRET_KEY on first use. Tokens are never signed or
+// accepted without a key; the server checks for it at startup.
+func signingKey() ([]byte, error) {
+	jwtKeyOnce.Do(func() {
+		jwtKey = []byte(os.Getenv("JWT_SECRET_KEY"))
+	})
+	if len(jwtKey) == 0 {
+		return nil, ErrNoJWTKey
 	}
-	jwtKey = []byte(jwtKeyStr)
+	return jwtKey, nil
 }
 
 type Claims struct {
@@ -25,6 +34,11 @@ type Claims struct {
 }
 
 func GenerateJWT(userID int) (string, error) {
+	key, err := signingKey()
+	if err != nil {
+		return "", err
+	}
+
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &Claims{
 		UserID: userID,
@@ -34,14 +48,18 @@ func GenerateJWT(userID int) (string, error) {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	return token.SignedString(key)
 }
 
 func ParseJWT(tokenStr string) (int, error) {
+	key, err := signingKey()
+	if err != nil {
+		return 0, err
+	}
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
+		return key, nil
 	})
 
 	if err != nil {
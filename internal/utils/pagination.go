@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const (
+	DefaultPageSize = 25
+	MaxPageSize     = 100
+)
+
+var ErrInvalidPage = errors.New("invalid pagination parameters")
+
+// Page is a window over a listing, taken from the page (1-based) and limit
+// query parameters. A zero Limit means the whole listing.
+type Page struct {
+	Number int
+	Limit  int
+}
+
+// ParsePage reads ?page= and ?limit=. Without either, the whole listing is
+// returned so that existing clients keep working; with only page, the
+// default page size applies.
+func ParsePage(r *http.Request) (Page, error) {
+	query := r.URL.Query()
+	page := Page{Number: 1}
+
+	if value := query.Get("page"); value != "" {
+		number, err := strconv.Atoi(value)
+		if err != nil || number < 1 {
+			return Page{}, ErrInvalidPage
+		}
+		page.Number = number
+		page.Limit = DefaultPageSize
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return Page{}, ErrInvalidPage
+		}
+		page.Limit = min(limit, MaxPageSize)
+	}
+
+	return page, nil
+}
+
+// Bounds returns the slice bounds of the page within a listing of total
+// items.
+func (p Page) Bounds(total int) (int, int) {
+	if p.Limit == 0 {
+		return 0, total
+	}
+
+	start := min((p.Number-1)*p.Limit, total)
+	end := min(start+p.Limit, total)
+	return start, end
+}
+
+// SetTotal reports the size of the whole listing in the X-Total-Count
+// header; the body holds only the requested page.
+func SetTotal(w http.ResponseWriter, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// DomainMatches reports whether rawURL points at domain or one of its
+// subdomains. A leading "www." is ignored on both sides.
+func DomainMatches(rawURL, domain string) bool {
+	if rawURL == "" {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	domain = strings.TrimPrefix(strings.ToLower(domain), "www.")
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
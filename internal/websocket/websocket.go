@@ -0,0 +1,52 @@
+package websocket
+
+import "time"
+
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+	ActionJoin        = "join"
+	ActionLeave       = "leave"
+	ActionChat        = "chat"
+	ActionLive        = "live"
+)
+
+const (
+	ReplyOK      = "ok"
+	ReplyError   = "error"
+	ReplyHistory = "history"
+	ReplyChat    = "chat"
+	ReplyLagged  = "lagged"
+)
+
+// Request is a message sent by the client. Subscriptions take a category or
+// a post_id, room actions take a room, and chat and live updates take text.
+type Request struct {
+	Action   string `json:"action"`
+	Category string `json:"category,omitempty"`
+	PostID   int    `json:"post_id,omitempty"`
+	Room     string `json:"room,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// Reply is every message sent to the client. Live updates from the event
+// bus use the same shape with the stream update types.
+type Reply struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+type Ack struct {
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ChatMessage is said in a category's chat room. Rooms exist only while
+// someone is in them and keep a short history for people who join.
+type ChatMessage struct {
+	Room     string    `json:"room"`
+	UserID   int       `json:"user_id"`
+	Username string    `json:"username"`
+	Text     string    `json:"text"`
+	Created  time.Time `json:"created"`
+}
@@ -0,0 +1,285 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Opcodes from RFC 6455, section 5.2.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close codes from RFC 6455, section 7.4.1.
+const (
+	CloseNormal        = 1000
+	CloseProtocolError = 1002
+	CloseUnsupported   = 1003
+	CloseTooLarge      = 1009
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	ErrBadHandshake = errors.New("not a websocket handshake")
+	ErrClosed       = errors.New("websocket closed")
+	errProtocol     = errors.New("websocket protocol error")
+	errTooLarge     = errors.New("websocket message too large")
+)
+
+// Conn is a server side WebSocket connection. ReadMessage must be called from
+// a single goroutine; WriteMessage may be called concurrently.
+type Conn struct {
+	conn         net.Conn
+	reader       *bufio.Reader
+	writeMu      sync.Mutex
+	writeTimeout time.Duration
+	maxMessage   int
+	closeOnce    sync.Once
+}
+
+// Upgrade completes the opening handshake and takes over the connection.
+// On failure an error response has already been written.
+func Upgrade(w http.ResponseWriter, r *http.Request, maxMessage int, writeTimeout time.Duration) (*Conn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "Expected a WebSocket upgrade", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, ErrBadHandshake
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		http.Error(w, "Invalid Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+
+	netConn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "WebSocket upgrade not supported", http.StatusInternalServerError)
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+
+	netConn.SetDeadline(time.Time{})
+	netConn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := io.WriteString(netConn, response); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	return &Conn{
+		conn:         netConn,
+		reader:       rw.Reader,
+		writeTimeout: writeTimeout,
+		maxMessage:   maxMessage,
+	}, nil
+}
+
+// ReadMessage returns the next text or binary message, reassembling
+// fragments. Pings are answered and pongs skipped; a close frame is echoed
+// and reported as ErrClosed. idle bounds the wait for each frame, so any
+// frame, a pong included, keeps the connection alive for another idle.
+func (c *Conn) ReadMessage(idle time.Duration) (int, []byte, error) {
+	var (
+		opcode  int
+		message []byte
+	)
+
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(idle))
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			switch {
+			case errors.Is(err, errProtocol):
+				c.CloseWithCode(CloseProtocolError, "")
+			case errors.Is(err, errTooLarge):
+				c.CloseWithCode(CloseTooLarge, "")
+			}
+			return 0, nil, err
+		}
+
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			code := CloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.CloseWithCode(code, "")
+			return 0, nil, ErrClosed
+		case opText, opBinary:
+			if message != nil {
+				c.CloseWithCode(CloseProtocolError, "")
+				return 0, nil, errProtocol
+			}
+			opcode = op
+			message = payload
+		case opContinuation:
+			if message == nil {
+				c.CloseWithCode(CloseProtocolError, "")
+				return 0, nil, errProtocol
+			}
+			if len(message)+len(payload) > c.maxMessage {
+				c.CloseWithCode(CloseTooLarge, "")
+				return 0, nil, errTooLarge
+			}
+			message = append(message, payload...)
+		default:
+			c.CloseWithCode(CloseProtocolError, "")
+			return 0, nil, errProtocol
+		}
+
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := int(header[0] & 0x0F)
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	// No extensions are negotiated, so the reserved bits must be clear, and
+	// clients must mask every frame.
+	if header[0]&0x70 != 0 || !masked {
+		return false, 0, nil, errProtocol
+	}
+	if opcode >= opClose && (!fin || length > 125) {
+		return false, 0, nil, errProtocol
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > uint64(c.maxMessage) {
+		return false, 0, nil, errTooLarge
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage sends data as a single text frame.
+func (c *Conn) WriteMessage(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// Ping sends a ping frame; the client's pong extends its read deadline.
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+	header := make([]byte, 0, 10)
+	header = append(header, 0x80|byte(opcode))
+	switch length := len(payload); {
+	case length <= 125:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	if _, err := c.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+// CloseWithCode sends a close frame, if the connection is still open, and
+// closes it.
+func (c *Conn) CloseWithCode(code int, reason string) {
+	c.closeOnce.Do(func() {
+		payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+		payload = append(payload, reason...)
+		c.writeFrame(opClose, payload)
+		c.conn.Close()
+	})
+}
+
+func (c *Conn) Close() {
+	c.CloseWithCode(CloseNormal, "")
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,399 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// connPair returns a server Conn and the client end of a loopback TCP
+// connection. TCP rather than net.Pipe, so both sides can write without
+// waiting for the other to read.
+func connPair(t *testing.T, maxMessage int) (*Conn, net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+
+	return &Conn{
+		conn:         server,
+		reader:       bufio.NewReader(server),
+		writeTimeout: time.Second,
+		maxMessage:   maxMessage,
+	}, client
+}
+
+// clientFrame encodes a frame the way a client sends it: masked, unless
+// masked is false to provoke a protocol error.
+func clientFrame(fin bool, opcode int, payload []byte, masked bool) []byte {
+	var frame []byte
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	frame = append(frame, first)
+
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	if !masked {
+		return append(frame, payload...)
+	}
+	mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+type serverFrame struct {
+	fin     bool
+	opcode  int
+	payload []byte
+}
+
+// readServerFrame decodes a frame written by Conn, checking that it is not
+// masked as RFC 6455 requires of servers.
+func readServerFrame(t *testing.T, r io.Reader) serverFrame {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	if header[1]&0x80 != 0 {
+		t.Fatal("server frame is masked")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(r, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatal(err)
+	}
+	return serverFrame{fin: header[0]&0x80 != 0, opcode: int(header[0] & 0x0F), payload: payload}
+}
+
+func closeCode(t *testing.T, f serverFrame) int {
+	t.Helper()
+	if f.opcode != opClose || len(f.payload) < 2 {
+		t.Fatalf("got opcode %#x with %d bytes, want a close frame", f.opcode, len(f.payload))
+	}
+	return int(binary.BigEndian.Uint16(f.payload))
+}
+
+func write(t *testing.T, w io.Writer, frames ...[]byte) {
+	t.Helper()
+	for _, frame := range frames {
+		if _, err := w.Write(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	// The example from RFC 6455, section 1.3.
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, 64, time.Second)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, data, err := conn.ReadMessage(time.Second); err == nil {
+			conn.WriteMessage(data)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{"valid", map[string]string{
+			"Connection": "keep-alive, Upgrade", "Upgrade": "websocket",
+			"Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ==",
+		}, http.StatusSwitchingProtocols},
+		{"not an upgrade", map[string]string{
+			"Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ==",
+		}, http.StatusBadRequest},
+		{"old version", map[string]string{
+			"Connection": "Upgrade", "Upgrade": "websocket",
+			"Sec-WebSocket-Version": "8", "Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ==",
+		}, http.StatusUpgradeRequired},
+		{"short key", map[string]string{
+			"Connection": "Upgrade", "Upgrade": "websocket",
+			"Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "c2hvcnQ=",
+		}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			var req strings.Builder
+			req.WriteString("GET / HTTP/1.1\r\nHost: test\r\n")
+			for name, value := range tt.headers {
+				req.WriteString(name + ": " + value + "\r\n")
+			}
+			req.WriteString("\r\n")
+			io.WriteString(conn, req.String())
+
+			reader := bufio.NewReader(conn)
+			resp, err := http.ReadResponse(reader, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != http.StatusSwitchingProtocols {
+				return
+			}
+
+			if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+				t.Errorf("got Sec-WebSocket-Accept %q", got)
+			}
+			write(t, conn, clientFrame(true, opText, []byte("echo"), true))
+			if f := readServerFrame(t, reader); f.opcode != opText || string(f.payload) != "echo" {
+				t.Errorf("got opcode %#x payload %q, want the echo", f.opcode, f.payload)
+			}
+		})
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	long := bytes.Repeat([]byte("x"), 300)
+
+	tests := []struct {
+		name    string
+		frames  [][]byte
+		opcode  int
+		message string
+	}{
+		{"masked text", [][]byte{clientFrame(true, opText, []byte("hello"), true)}, opText, "hello"},
+		{"binary", [][]byte{clientFrame(true, opBinary, []byte{1, 2, 3}, true)}, opBinary, "\x01\x02\x03"},
+		{"empty", [][]byte{clientFrame(true, opText, nil, true)}, opText, ""},
+		{"16-bit length", [][]byte{clientFrame(true, opText, long, true)}, opText, string(long)},
+		{"fragments", [][]byte{
+			clientFrame(false, opText, []byte("hel"), true),
+			clientFrame(false, opContinuation, []byte("l"), true),
+			clientFrame(true, opContinuation, []byte("o"), true),
+		}, opText, "hello"},
+		{"pong skipped", [][]byte{
+			clientFrame(true, opPong, nil, true),
+			clientFrame(true, opText, []byte("after"), true),
+		}, opText, "after"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, client := connPair(t, 512)
+			write(t, client, tt.frames...)
+
+			opcode, data, err := conn.ReadMessage(time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opcode != tt.opcode || string(data) != tt.message {
+				t.Errorf("got opcode %#x message %q, want %#x %q", opcode, data, tt.opcode, tt.message)
+			}
+		})
+	}
+}
+
+func TestReadMessageErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames [][]byte
+		err    error
+		code   int
+	}{
+		{"unmasked", [][]byte{clientFrame(true, opText, []byte("hi"), false)}, errProtocol, CloseProtocolError},
+		{"reserved bits", [][]byte{func() []byte {
+			frame := clientFrame(true, opText, []byte("hi"), true)
+			frame[0] |= 0x40
+			return frame
+		}()}, errProtocol, CloseProtocolError},
+		{"unknown opcode", [][]byte{clientFrame(true, 0x3, []byte("hi"), true)}, errProtocol, CloseProtocolError},
+		{"fragmented control frame", [][]byte{clientFrame(false, opPing, nil, true)}, errProtocol, CloseProtocolError},
+		{"continuation first", [][]byte{clientFrame(true, opContinuation, []byte("hi"), true)}, errProtocol, CloseProtocolError},
+		{"new message inside fragments", [][]byte{
+			clientFrame(false, opText, []byte("a"), true),
+			clientFrame(true, opText, []byte("b"), true),
+		}, errProtocol, CloseProtocolError},
+		{"frame too large", [][]byte{clientFrame(true, opText, make([]byte, 17), true)}, errTooLarge, CloseTooLarge},
+		{"fragments too large", [][]byte{
+			clientFrame(false, opText, make([]byte, 10), true),
+			clientFrame(true, opContinuation, make([]byte, 10), true),
+		}, errTooLarge, CloseTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, client := connPair(t, 16)
+			write(t, client, tt.frames...)
+
+			if _, _, err := conn.ReadMessage(time.Second); !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if code := closeCode(t, readServerFrame(t, client)); code != tt.code {
+				t.Errorf("got close code %d, want %d", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestPingIsAnsweredBetweenFragments(t *testing.T) {
+	conn, client := connPair(t, 64)
+	write(t, client,
+		clientFrame(false, opText, []byte("hel"), true),
+		clientFrame(true, opPing, []byte("are you there"), true),
+		clientFrame(true, opContinuation, []byte("lo"), true),
+	)
+
+	_, data, err := conn.ReadMessage(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("got message %q", data)
+	}
+
+	pong := readServerFrame(t, client)
+	if pong.opcode != opPong || string(pong.payload) != "are you there" {
+		t.Errorf("got opcode %#x payload %q, want the pong", pong.opcode, pong.payload)
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	conn, client := connPair(t, 64)
+	payload := binary.BigEndian.AppendUint16(nil, 1001)
+	write(t, client, clientFrame(true, opClose, append(payload, "bye"...), true))
+
+	if _, _, err := conn.ReadMessage(time.Second); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got error %v, want ErrClosed", err)
+	}
+	if code := closeCode(t, readServerFrame(t, client)); code != 1001 {
+		t.Errorf("got close code %d, want the client's 1001 echoed", code)
+	}
+
+	// The server closes the TCP connection after its close frame, and sends
+	// no second close frame when closed again.
+	conn.Close()
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	if n, err := client.Read(make([]byte, 1)); n != 0 || err == nil {
+		t.Errorf("read %d bytes and %v after the close frame, want EOF", n, err)
+	}
+}
+
+func TestPongsKeepConnectionAlive(t *testing.T) {
+	const idle = 100 * time.Millisecond
+	conn, client := connPair(t, 64)
+
+	// The message arrives well after idle has passed since ReadMessage was
+	// called, but a pong comes in before every idle runs out.
+	go func() {
+		for i := 0; i < 5; i++ {
+			time.Sleep(idle / 2)
+			client.Write(clientFrame(true, opPong, nil, true))
+		}
+		client.Write(clientFrame(true, opText, []byte("still here"), true))
+	}()
+
+	start := time.Now()
+	_, data, err := conn.ReadMessage(idle)
+	if err != nil {
+		t.Fatalf("got error %v after %s", err, time.Since(start))
+	}
+	if string(data) != "still here" {
+		t.Errorf("got message %q", data)
+	}
+}
+
+func TestIdleConnectionTimesOut(t *testing.T) {
+	conn, _ := connPair(t, 64)
+
+	var netErr net.Error
+	if _, _, err := conn.ReadMessage(50 * time.Millisecond); !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("got error %v, want a timeout", err)
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	for _, size := range []int{0, 125, 126, 0xFFFF, 0x10000} {
+		conn, client := connPair(t, 64)
+		data := bytes.Repeat([]byte("y"), size)
+
+		go conn.WriteMessage(data)
+		f := readServerFrame(t, client)
+		if !f.fin || f.opcode != opText || !bytes.Equal(f.payload, data) {
+			t.Errorf("size %d: got fin %v opcode %#x and %d bytes", size, f.fin, f.opcode, len(f.payload))
+		}
+	}
+}
+
+func TestPing(t *testing.T) {
+	conn, client := connPair(t, 64)
+	if err := conn.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if f := readServerFrame(t, client); f.opcode != opPing || len(f.payload) != 0 {
+		t.Errorf("got opcode %#x payload %q, want an empty ping", f.opcode, f.payload)
+	}
+}
@@ -0,0 +1,280 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"redditclone/internal/block"
+	"redditclone/internal/events"
+	"redditclone/internal/post"
+	"redditclone/internal/stream"
+	"redditclone/internal/user"
+	"redditclone/internal/utils"
+)
+
+const (
+	maxMessageSize = 4096
+	maxTextLength  = 500
+	maxRoomLength  = 64
+	// maxSessionRooms bounds how many chat rooms one session may be in.
+	maxSessionRooms = 10
+	bufferSize      = 64
+	writeTimeout    = 10 * time.Second
+	pingInterval    = 30 * time.Second
+	// readTimeout is how long a client may stay silent; pings are sent
+	// often enough for its pongs to keep it alive.
+	readTimeout = 2 * pingInterval
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrNotPostAuthor  = errors.New("only the post's author can post live updates")
+	ErrUnknownRoom    = errors.New("chat rooms exist only for categories with posts")
+	ErrTooManyRooms   = errors.New("you are in too many chat rooms")
+)
+
+type Handler struct {
+	bus          *events.Bus
+	postService  post.Service
+	userService  user.Service
+	blockService block.Service
+	filters      *stream.Filters
+	rooms        *rooms
+	logger       *log.Logger
+}
+
+func NewWebSocketHandler(bus *events.Bus, postService post.Service, userService user.Service, blockService block.Service, logger *log.Logger) *Handler {
+	return &Handler{
+		bus:          bus,
+		postService:  postService,
+		userService:  userService,
+		blockService: blockService,
+		filters:      stream.NewFilters(postService),
+		rooms:        newRooms(),
+		logger:       logger,
+	}
+}
+
+// AddListFilter hides new posts and their scores from the viewers the
+// listings hide them from, see stream.Filters.
+func (h *Handler) AddListFilter(filter post.ListFilter) {
+	h.filters.AddListFilter(filter)
+}
+
+// AddViewFilter hides or adjusts every update about a post, as the post
+// page does.
+func (h *Handler) AddViewFilter(filter post.ListFilter) {
+	h.filters.AddViewFilter(filter)
+}
+
+// AddCommentFilter hides new comments as the post page does.
+func (h *Handler) AddCommentFilter(filter post.CommentFilter) {
+	h.filters.AddCommentFilter(filter)
+}
+
+// Connect upgrades an authenticated request to a WebSocket session. As with
+// the event stream, the JWT may be passed as ?token= because browsers cannot
+// set headers on a WebSocket.
+func (h *Handler) Connect(w http.ResponseWriter, r *http.Request) {
+	h.logger.Println("Opening WebSocket session")
+
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = header[len("Bearer "):]
+	}
+	if token == "" {
+		http.Error(w, "Missing token", http.StatusUnauthorized)
+		return
+	}
+	userID, err := utils.ParseJWT(token)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+	u, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := Upgrade(w, r, maxMessageSize, writeTimeout)
+	if err != nil {
+		return
+	}
+
+	s := newSession(conn, u.ID, u.Username, h.filters)
+	sub := h.bus.SubscribeAsync(bufferSize, stream.Streamed...)
+	go s.pump(sub)
+
+	defer func() {
+		close(s.done)
+		sub.Close()
+		for name := range s.rooms {
+			h.rooms.leave(name, s)
+		}
+		conn.Close()
+	}()
+
+	for {
+		opcode, data, err := conn.ReadMessage(readTimeout)
+		if err != nil {
+			return
+		}
+		if opcode != opText {
+			conn.CloseWithCode(CloseUnsupported, "text messages only")
+			return
+		}
+
+		var req Request
+		if err := json.Unmarshal(data, &req); err != nil {
+			err = s.reply(Reply{Type: ReplyError, Data: Ack{Error: ErrInvalidRequest.Error()}})
+		} else {
+			err = h.handle(s, req)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handle runs a single request. Only a failure to write the reply is
+// returned; rejected requests are reported to the client.
+func (h *Handler) handle(s *session, req Request) error {
+	var err error
+	switch req.Action {
+	case ActionSubscribe, ActionUnsubscribe:
+		err = h.subscribe(s, req)
+	case ActionJoin:
+		var history []ChatMessage
+		history, err = h.join(s, req.Room)
+		if err == nil {
+			return s.reply(Reply{Type: ReplyHistory, Data: history})
+		}
+	case ActionLeave:
+		s.mu.Lock()
+		delete(s.rooms, req.Room)
+		s.mu.Unlock()
+		h.rooms.leave(req.Room, s)
+	case ActionChat:
+		err = h.chat(s, req)
+	case ActionLive:
+		err = h.live(s, req)
+	default:
+		err = ErrInvalidRequest
+	}
+
+	if err != nil {
+		return s.reply(Reply{Type: ReplyError, Data: Ack{Action: req.Action, Error: err.Error()}})
+	}
+	return s.reply(Reply{Type: ReplyOK, Data: Ack{Action: req.Action}})
+}
+
+func (h *Handler) subscribe(s *session, req Request) error {
+	if req.Category == "" && req.PostID == 0 {
+		return ErrInvalidRequest
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscribe := req.Action == ActionSubscribe
+	if req.Category != "" {
+		if subscribe {
+			s.topics.Categories[req.Category] = true
+		} else {
+			delete(s.topics.Categories, req.Category)
+		}
+	}
+	if req.PostID != 0 {
+		if subscribe {
+			s.topics.Posts[req.PostID] = true
+		} else {
+			delete(s.topics.Posts, req.PostID)
+		}
+	}
+	return nil
+}
+
+// join enters the chat room of a category and returns its recent history,
+// without messages from users the member has blocked. Only categories that
+// have posts have rooms, so clients cannot make up rooms of their own.
+func (h *Handler) join(s *session, name string) ([]ChatMessage, error) {
+	if name == "" || utf8.RuneCountInString(name) > maxRoomLength {
+		return nil, ErrInvalidRequest
+	}
+	if posts, err := h.postService.GetPostsByCategory(name); err != nil || len(posts) == 0 {
+		return nil, ErrUnknownRoom
+	}
+
+	s.mu.Lock()
+	if !s.rooms[name] && len(s.rooms) >= maxSessionRooms {
+		s.mu.Unlock()
+		return nil, ErrTooManyRooms
+	}
+	s.rooms[name] = true
+	s.mu.Unlock()
+
+	history := []ChatMessage{}
+	for _, msg := range h.rooms.join(name, s) {
+		if !h.blockService.HasBlocked(s.userID, msg.UserID) {
+			history = append(history, msg)
+		}
+	}
+	return history, nil
+}
+
+func (h *Handler) chat(s *session, req Request) error {
+	text := strings.TrimSpace(req.Text)
+	if text == "" || utf8.RuneCountInString(text) > maxTextLength {
+		return ErrInvalidRequest
+	}
+
+	msg := ChatMessage{
+		Room:     req.Room,
+		UserID:   s.userID,
+		Username: s.username,
+		Text:     text,
+		Created:  time.Now(),
+	}
+	members, err := h.rooms.say(s, msg)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if member != s && !h.blockService.HasBlocked(member.userID, s.userID) {
+			member.enqueue(Reply{Type: ReplyChat, Data: msg})
+		}
+	}
+	return nil
+}
+
+// live adds an update to a live thread. It goes out over the event bus, so
+// both WebSocket and event stream subscribers of the post receive it.
+func (h *Handler) live(s *session, req Request) error {
+	text := strings.TrimSpace(req.Text)
+	if text == "" || utf8.RuneCountInString(text) > maxTextLength {
+		return ErrInvalidRequest
+	}
+
+	p, err := h.postService.GetPostByID(req.PostID)
+	if err != nil {
+		return err
+	}
+	if p.AuthorID != s.userID {
+		return ErrNotPostAuthor
+	}
+
+	h.bus.Publish(events.LiveThreadUpdated, stream.LiveUpdate{
+		PostID:   p.ID,
+		AuthorID: s.userID,
+		Text:     text,
+		Created:  time.Now(),
+	})
+	return nil
+}
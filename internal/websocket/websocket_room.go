@@ -0,0 +1,128 @@
+package websocket
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	historySize = 50
+	// Each user may say chatBurst messages in a room at once and one more
+	// every chatInterval after that.
+	chatBurst    = 5
+	chatInterval = 2 * time.Second
+)
+
+var (
+	ErrNotInRoom   = errors.New("join the room first")
+	ErrRateLimited = errors.New("you are sending messages too fast")
+)
+
+// limiter is a token bucket.
+type limiter struct {
+	tokens float64
+	last   time.Time
+}
+
+func (l *limiter) allow(now time.Time) bool {
+	if l.last.IsZero() {
+		l.tokens = chatBurst
+	} else {
+		l.tokens += float64(now.Sub(l.last)) / float64(chatInterval)
+		if l.tokens > chatBurst {
+			l.tokens = chatBurst
+		}
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+type room struct {
+	members  map[*session]bool
+	history  []ChatMessage
+	limiters map[int]*limiter
+}
+
+// rooms holds the chat rooms that currently have members.
+type rooms struct {
+	mu    sync.Mutex
+	rooms map[string]*room
+}
+
+func newRooms() *rooms {
+	return &rooms{
+		rooms: make(map[string]*room),
+	}
+}
+
+// join adds s to the room, creating it if needed, and returns its history.
+func (rs *rooms) join(name string, s *session) []ChatMessage {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.rooms[name]
+	if !ok {
+		r = &room{
+			members:  make(map[*session]bool),
+			limiters: make(map[int]*limiter),
+		}
+		rs.rooms[name] = r
+	}
+	r.members[s] = true
+
+	return append([]ChatMessage(nil), r.history...)
+}
+
+// leave removes s from the room; the last one out closes it and its history
+// is forgotten.
+func (rs *rooms) leave(name string, s *session) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.rooms[name]
+	if !ok {
+		return
+	}
+	delete(r.members, s)
+	if len(r.members) == 0 {
+		delete(rs.rooms, name)
+	}
+}
+
+// say records msg in the room's history and returns the members to deliver
+// it to.
+func (rs *rooms) say(s *session, msg ChatMessage) ([]*session, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.rooms[msg.Room]
+	if !ok || !r.members[s] {
+		return nil, ErrNotInRoom
+	}
+
+	l, ok := r.limiters[s.userID]
+	if !ok {
+		l = &limiter{}
+		r.limiters[s.userID] = l
+	}
+	if !l.allow(msg.Created) {
+		return nil, ErrRateLimited
+	}
+
+	r.history = append(r.history, msg)
+	if len(r.history) > historySize {
+		r.history = append(r.history[:0:0], r.history[len(r.history)-historySize:]...)
+	}
+
+	members := make([]*session, 0, len(r.members))
+	for member := range r.members {
+		members = append(members, member)
+	}
+	return members, nil
+}
@@ -0,0 +1,124 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"redditclone/internal/events"
+	"redditclone/internal/stream"
+)
+
+// session is one connected client. Updates from the bus and chat messages
+// from rooms are written by its pump goroutine; replies to the client's own
+// requests are written by the reading goroutine.
+type session struct {
+	conn     *Conn
+	userID   int
+	username string
+	filters  *stream.Filters
+
+	mu     sync.Mutex
+	topics stream.Topics
+	rooms  map[string]bool
+
+	out     chan []byte
+	dropped atomic.Int64
+	done    chan struct{}
+}
+
+func newSession(conn *Conn, userID int, username string, filters *stream.Filters) *session {
+	return &session{
+		conn:     conn,
+		userID:   userID,
+		username: username,
+		filters:  filters,
+		topics:   stream.NewTopics(userID),
+		rooms:    make(map[string]bool),
+		out:      make(chan []byte, bufferSize),
+		done:     make(chan struct{}),
+	}
+}
+
+// enqueue hands a message to the pump without blocking; a client that has
+// fallen behind loses it and is told how many it missed.
+func (s *session) enqueue(reply Reply) {
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return
+	}
+
+	select {
+	case s.out <- data:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *session) reply(reply Reply) error {
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return err
+	}
+	return s.conn.WriteMessage(data)
+}
+
+// wants reports whether an update from the bus should be forwarded. Unlike
+// the event stream, nothing but notifications and messages is sent before the client
+// subscribes to something.
+func (s *session) wants(u stream.Update) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	personal := u.Type == stream.UpdateNotification || u.Type == stream.UpdateMessage
+	if !personal && len(s.topics.Categories) == 0 && len(s.topics.Posts) == 0 {
+		return false
+	}
+	return s.topics.Match(u)
+}
+
+// pump writes updates and queued messages until the session ends, pinging
+// the client so a dead connection is noticed by the reader's deadline.
+func (s *session) pump(sub *events.Subscription) {
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		var (
+			data []byte
+			err  error
+		)
+		select {
+		case <-s.done:
+			return
+		case <-ping.C:
+			err = s.conn.Ping()
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			u, ok := stream.FromEvent(e)
+			if !ok || !s.wants(u) {
+				break
+			}
+			if u, ok = s.filters.Apply(s.userID, u); !ok {
+				break
+			}
+			data, err = json.Marshal(Reply{Type: u.Type, Data: u.Data})
+		case data = <-s.out:
+		}
+		if err == nil && data != nil {
+			if dropped := sub.Dropped() + s.dropped.Swap(0); dropped > 0 {
+				err = s.reply(Reply{Type: ReplyLagged, Data: map[string]int64{"dropped": dropped}})
+			}
+			if err == nil {
+				err = s.conn.WriteMessage(data)
+			}
+		}
+		if err != nil {
+			s.conn.Close()
+			return
+		}
+	}
+}